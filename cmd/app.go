@@ -1,100 +1,202 @@
 package main
 
 import (
-	"database/sql"              // импорт стандартного пакета для работы с базой данных
-	"encoding/json"             // импорт пакета для работы с json
-	"fmt"                       // импорт пакета для форматированного вывода
-	"log"                       // импорт пакета для логирования
-	"net/http"                  // импорт пакета для работы с http протоколом
-	"wb_test/internal/cache"    // импорт пакета для работы с кэшем
-	"wb_test/internal/database" // импорт локального пакета для работы с базой данных
-
-	"github.com/gorilla/mux" // импорт библиотеки gorilla/mux для маршрутизации http запросов
+	"context"                                     // импорт пакета для отмены по сигналу остановки
+	"errors"                                      // импорт пакета для работы с ошибками
+	"expvar"                                      // импорт пакета для публикации счетчиков
+	"flag"                                        // импорт пакета для разбора флагов командной строки
+	"fmt"                                         // импорт пакета для форматированного вывода
+	"log"                                         // импорт пакета для логирования до настройки slog
+	"log/slog"                                    // импорт пакета структурированного логирования
+	"os"                                          // импорт пакета для работы с аргументами командной строки
+	"os/signal"                                   // импорт пакета для обработки сигналов остановки
+	"syscall"                                     // импорт пакета с номерами сигналов
+	"wb_test/internal/cache"                      // импорт пакета для работы с кэшем
+	"wb_test/internal/config"                     // импорт пакета с настройками сервиса
+	"wb_test/internal/database"                   // импорт локального пакета для работы с базой данных
+	"wb_test/internal/health"                     // импорт пакета для проверки зависимостей
+	httpserver "wb_test/internal/http"            // импорт пакета http сервера
+	"wb_test/internal/logging"                    // импорт пакета для настройки логирования
+	subscriber "wb_test/internal/nats/subscriber" // импорт пакета для подписки на канал nats-streaming
+	"wb_test/internal/tracing"                    // импорт пакета для настройки трассировки
+
+	"github.com/prometheus/client_golang/prometheus"            // импорт библиотеки метрик prometheus
+	"github.com/prometheus/client_golang/prometheus/collectors" // импорт сборщика метрик пула соединений с БД
 )
 
-var db *sql.DB // объявляем переменную для работы с базой данных
-
 func main() {
-	cache.InitCache() // инициализируем кэш
-
-	var err error
-	db, err = database.ConnectDB() // подключаемся к базе данных
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:]) // загружаем настройки из файла, окружения и флагов
 	if err != nil {
-		log.Fatalf("Не удалось подключиться к базе данных: %v", err) // выбрасываем ошибку, если не получилось подключиться к базе данных
+		log.Fatalf("Не удалось загрузить конфигурацию: %v", err) // выбрасываем ошибку, если конфигурация некорректна
 	}
-
-	err = loadCacheFromDB(db) // восстанавливаем кэш из базы данных
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil { // дальше все записи лога пишутся через slog
+		log.Fatalf("Не удалось настроить логирование: %v", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing) // настраиваем экспорт трасс
 	if err != nil {
-		log.Fatalf("Не удалось загрузить кэш из базы данных: %v", err) // выбрасываем ошибку, если не получилось восстановить кэш
+		fatal("Не удалось настроить трассировку", err)
 	}
 
-	r := mux.NewRouter()                                         // создаем новый роутер с использованием библиотеки gorilla/mux
-	r.HandleFunc("/orders/{id}", getOrderHandler).Methods("GET") // добавляем обработчик GET запроса по пути /orders/{id}
-	r.HandleFunc("/orders", createOrderHandler).Methods("POST")  // добавляем обработчик POST запроса по пути  /orders
+	orderCache := cache.New(cfg.Cache) // создаем кэш с ограничениями из конфигурации
+	defer orderCache.Close()
+	warmup := &cache.Warmup{}                                                          // состояние загрузки кэша из базы данных
+	expvar.Publish("cache", expvar.Func(func() any { return orderCache.Stats() }))     // публикуем статистику кэша в /debug/vars
+	expvar.Publish("cache_warmup", expvar.Func(func() any { return warmup.Status() })) // публикуем прогресс загрузки кэша
 
-	fmt.Println("Сервер работает на порту 8000")
-	log.Fatal(http.ListenAndServe(":8000", r)) // запускаем сервер на порту  8000
-}
+	db, err := database.ConnectDB(cfg.Database) // подключаемся к базе данных
+	if err != nil {
+		fatal("Не удалось подключиться к базе данных", err) // выбрасываем ошибку, если не получилось подключиться к базе данных
+	}
 
-func getOrderHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)                               // получаем переменные из URL
-	orderUID := vars["id"]                            // получаем ID заказа из переменных
-	log.Printf("Получение заказа с ID: %s", orderUID) // логируем получение заказа по ID
+	prometheus.MustRegister(cache.NewCollector(orderCache), collectors.NewDBStatsCollector(db, "orders")) // публикуем метрики кэша и пула соединений
 
-	order, found := cache.GetOrderFromCache(orderUID) // получаем заказ из кэша
-	if found {
-		log.Printf("Заказ найден в кэше: %+v", order) // логируем нахождение заказа в кэше
-		json.NewEncoder(w).Encode(order)              // отпраляем json ответа с найденным заказом
+	if flag.Arg(0) == "migrate" { // выполняем подкоманду migrate вместо запуска сервиса
+		err = runMigrate(db, flag.Args()[1:])
+		db.Close()
+		if err != nil {
+			fatal("Ошибка миграции", err)
+		}
 		return
 	}
 
-	order, err := database.GetOrderFromDB(db, orderUID) // получаем заказ из базы данных
-	if err != nil {
-		log.Printf("Ошибка получения заказа из БД.: %v", err)      // логируем ошибку получения заказа из БД
-		http.Error(w, err.Error(), http.StatusInternalServerError) // возвращаем http ошибки в случае ошибки БД
-		return
+	if cfg.Database.AutoMigrate {
+		applied, err := database.MigrateUp(db) // применяем новые миграции схемы
+		if err != nil {
+			fatal("Не удалось применить миграции", err) // выбрасываем ошибку, если схема не обновилась
+		}
+		slog.Info("Миграции применены", "applied", len(applied))
 	}
-	if order == nil {
-		log.Printf("Заказ не найден в БД") // логируем ошибку получения заказа из БД
-		http.NotFound(w, r)                // возвращаем ошибку 404
-		return
+
+	orders := database.NewPostgresRepository(db, cfg.Database.UpsertPolicy) // создаем хранилище заказов
+
+	loadCache := func() error { // загрузка кэша: из снимка с догрузкой изменений или целиком из базы данных
+		if cfg.Cache.SnapshotPath != "" {
+			return warmup.LoadCacheFromSnapshot(orderCache, db, cfg.Cache.SnapshotPath, cfg.NATS.Channel)
+		}
+		return warmup.LoadCacheFromDB(orderCache, db)
+	}
+	if cfg.Cache.WarmupBackground {
+		go func() {
+			if err := loadCache(); err != nil { // загружаем кэш, пока сервер уже отвечает на запросы
+				slog.Error("Не удалось загрузить кэш из базы данных", "error", err)
+			}
+		}()
+	} else {
+		err = loadCache() // восстанавливаем кэш из базы данных до запуска сервера
+		if err != nil {
+			fatal("Не удалось загрузить кэш из базы данных", err) // выбрасываем ошибку, если не получилось восстановить кэш
+		}
 	}
 
-	cache.SaveOrderToCache(order)                                   // сохраняем заказ в кэш
-	log.Printf("Заказ получен из БД и сохранен в кэше: %+v", order) // логируем успешное получение и сохранение заказа
-	json.NewEncoder(w).Encode(order)                                // отпраляем json ответа с найденным заказом
-}
+	var listener *database.OrderListener
+	if cfg.Cache.RefreshOnNotify {
+		listener, err = cache.RefreshOnNotify(orderCache, db, cfg.Database) // обновляем кэш при изменении заказов в БД
+		if err != nil {
+			fatal("Не удалось подписаться на изменения заказов", err)
+		}
+	}
 
-func createOrderHandler(w http.ResponseWriter, r *http.Request) {
-	var order database.Order                      // объявляем переменную для нового заказа типа database.Order
-	err := json.NewDecoder(r.Body).Decode(&order) // декодируем JSON тела запроса в структуру заказа
-	if err != nil {
-		log.Printf("Ошибка получения заказа из JSON: %v", err) // логируем ошибку декодирования JSON
-		http.Error(w, err.Error(), http.StatusBadRequest)      // возвращаем http ошибки 400 в случае некорректного запроса
-		return
+	var snapshotter *cache.Snapshotter
+	if cfg.Cache.SnapshotPath != "" {
+		snapshotter = cache.NewSnapshotter(cfg.Cache.SnapshotPath, cfg.NATS.Channel, orderCache, db)
+		go snapshotter.Run(cfg.Cache.SnapshotInterval) // периодически сохраняем снимок кэша на диск
 	}
 
-	err = database.SaveOrder(db, &order) // сохраняем заказ в БД
+	nc, err := subscriber.ConnectNATS(cfg.NATS) // подключаемся к nats-streaming
 	if err != nil {
-		log.Printf("Ошибка сохранения заказа в БД: %v", err)       // логируем ошибку сохранения заказа в БД
-		http.Error(w, err.Error(), http.StatusInternalServerError) // возвращаем http ошибки в случае ошибки сохранения заказа в БД
-		return
+		fatal("Не удалось подключиться к nats", err) // выбрасываем ошибку, если не получилось подключиться к nats
 	}
 
-	cache.SaveOrderToCache(&order) // сохраняем заказ в кэш
-
-	w.WriteHeader(http.StatusCreated) // устанавливаем HTTP код 201 - созданный заказ
-	json.NewEncoder(w).Encode(order)  // отпраляем json ответа с созданным заказом
-}
+	dlq := subscriber.NewDeadLetterQueue(nc, db, cfg.NATS.DeadLetterChannel) // создаем dead-letter очередь
 
-func loadCacheFromDB(db *sql.DB) error {
-	orders, err := database.GetAllOrdersFromDB(db) // получаем все заказы из базы данных
+	drainer := &subscriber.Drainer{}                                                                               // учитываем обрабатываемые сообщения для остановки
+	sub, err := subscriber.Subscribe(nc, cfg.NATS, drainer.Wrap(subscriber.OrderHandler(orders, orderCache, dlq))) // подписываемся на канал с заказами
 	if err != nil {
-		return fmt.Errorf("Ошибка загрузки заказов из базы данных: %v", err)
+		fatal("Не удалось подписаться на канал "+cfg.NATS.Channel, err) // выбрасываем ошибку, если не получилось подписаться
 	}
 
-	for _, order := range orders {
-		cache.SaveOrderToCache(order) // сохраняем каждый заказ в кэш
+	monitor := health.NewMonitor(cfg.HTTP.ReadyTimeout) // проверки зависимостей для /readyz и /status
+	monitor.Register("database", db.PingContext)
+	monitor.Register("nats", func(context.Context) error { return subscriber.Check(nc, sub) })
+	monitor.Register("cache", func(context.Context) error {
+		if p := warmup.Status(); !p.Ready {
+			if p.Error != "" {
+				return errors.New(p.Error)
+			}
+			return fmt.Errorf("Кэш загружается: %d из %d заказов", p.Loaded, p.Total)
+		}
+		return nil
+	})
+
+	server := httpserver.New(cfg.HTTP, httpserver.Deps{ // создаем http сервер со всеми маршрутами
+		Orders:        orders,
+		Cache:         orderCache,
+		Warmup:        warmup,
+		Resync:        func() error { return warmup.StartResync(orderCache, db) },
+		DeadLetters:   dlq,
+		OrdersChannel: cfg.NATS.Channel,
+		Health:        monitor,
+		Alive:         subscriber.Alive, // потерянная сессия nats-streaming не восстанавливается, /healthz просит перезапуск
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM) // контекст отменяется при получении SIGINT или SIGTERM
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Сервер запущен", "addr", server.Addr())
+		serverErr <- server.ListenAndServe() // запускаем сервер на настроенном адресе
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		slog.Error("Http сервер остановлен с ошибкой", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		slog.Info("Получен сигнал остановки, завершаем работу")
+	}
+	stop() // повторный сигнал завершит процесс сразу
+
+	// Останавливаемся по порядку: новые запросы и сообщения перестают приниматься, начатые доводятся до конца,
+	// снимок кэша сохраняется, пока открыта база данных, соединения закрываются последними
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Не удалось дождаться завершения http запросов", "error", err)
+	}
+	if err := drainer.Drain(shutdownCtx); err != nil {
+		slog.Warn("Не удалось дождаться сохранения заказов из nats", "error", err)
+	}
+	if err := sub.Close(); err != nil { // Close, а не Unsubscribe: durable подписка продолжится с того же места после перезапуска
+		slog.Error("Ошибка закрытия подписки nats", "error", err)
+	}
+	if snapshotter != nil {
+		snapshotter.Close()
+		if err := snapshotter.Save(); err != nil { // сохраняем последний снимок, чтобы перезапуск был быстрым
+			slog.Error("Не удалось сохранить снимок кэша", "error", err)
+		}
 	}
-	return nil
+	if listener != nil {
+		listener.Close()
+	}
+	if err := nc.Close(); err != nil {
+		slog.Error("Ошибка закрытия соединения с nats", "error", err)
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("Ошибка закрытия соединения с базой данных", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil { // отправляем оставшиеся spans
+		slog.Error("Не удалось отправить трассы", "error", err)
+	}
+	slog.Info("Сервис остановлен")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// fatal пишет ошибку в лог и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql"              // импорт стандартного пакета для работы с базой данных
+	"errors"                    // импорт пакета для работы с ошибками
+	"fmt"                       // импорт пакета для форматированного вывода
+	"strconv"                   // импорт пакета для преобразования строк в числа
+	"wb_test/internal/database" // импорт локального пакета для работы с базой данных
+)
+
+const migrateUsage = "использование: migrate up | down [N] | status | force VERSION"
+
+// runMigrate выполняет подкоманду migrate: up, down [N], status или force VERSION
+func runMigrate(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db) // применяем все новые миграции
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Схема базы данных актуальна")
+		}
+		for _, version := range applied {
+			fmt.Printf("Применена миграция %04d\n", version)
+		}
+
+	case "down":
+		steps := 1 // по умолчанию откатываем одну последнюю миграцию
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("Некорректное количество шагов: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := database.MigrateDown(db, steps) // откатываем последние миграции
+		if err != nil {
+			return err
+		}
+		for _, version := range reverted {
+			fmt.Printf("Откачена миграция %04d\n", version)
+		}
+
+	case "status":
+		states, err := database.MigrationStatus(db) // получаем состояние миграций
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			status := "не применена"
+			if state.AppliedAt != nil {
+				status = "применена " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", state.Version, state.Name, status)
+		}
+
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("Некорректная версия: %s", args[1])
+		}
+		if err := database.ForceMigrationVersion(db, version); err != nil { // записываем версию без выполнения SQL
+			return err
+		}
+		fmt.Printf("Версия схемы установлена в %04d\n", version)
+
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
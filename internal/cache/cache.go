@@ -1,54 +1,246 @@
 package cache
 
 import (
-	"database/sql"              // Импортируем пакет для работы с SQL базами данных
+	"bytes"                     // Импортируем пакет для копирования срезов
+	"crypto/sha256"             // Импортируем пакет для вычисления хэша
+	"encoding/hex"              // Импортируем пакет для шестнадцатеричного кодирования
+	"encoding/json"             // Импортируем пакет для работы с json
+	"io"                        // Импортируем пакет с интерфейсом вывода
+	"slices"                    // Импортируем пакет для сортировки срезов
 	"sync"                      // Импортируем пакет для синхронизации goroutine
+	"time"                      // Импортируем пакет для работы со временем
+	"wb_test/internal/config"   // Импортируем пакет с настройками сервиса
 	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
 )
 
-// Cache представляет структуру кэша для хранения заказов
+// OrderCache описывает кэш заказов, с которым работают обработчики и подписчик
+type OrderCache interface {
+	Get(orderUID string) (*database.Order, bool) // возвращает заказ и флаг его наличия
+	GetEncoded(orderUID string) (Encoded, bool)  // возвращает заказ в JSON с ETag и флаг его наличия
+	Set(order *database.Order)                   // сохраняет заказ
+	Fill(order *database.Order) bool             // сохраняет заказ, прочитанный из БД или снимка, если он не записан через Set
+	Delete(orderUID string) bool                 // удаляет заказ и сообщает, был ли он в кэше
+	Purge()                                      // удаляет все заказы
+	Len() int                                    // возвращает количество заказов
+	Range(fn func(order *database.Order) bool)   // вызывает fn для каждого заказа, пока fn возвращает true
+	Keys() []string                              // возвращает отсортированные UID заказов
+	Peek(orderUID string) (EntryInfo, bool)      // возвращает запись без учета в статистике и политике вытеснения
+	Stats() Stats                                // возвращает статистику использования
+}
+
+// Cache представляет структуру кэша для хранения заказов.
+// Заказы распределяются по сегментам по хэшу OrderUID, у каждого сегмента своя блокировка,
+// поэтому запись в один сегмент не останавливает чтение из остальных.
+// Кэш хранит собственные копии заказов и возвращает копии, поэтому изменение
+// полученного или переданного заказа не влияет на других читателей.
+// Лимиты количества и объема делятся между сегментами поровну,
+// при превышении лимитов сегмента заказы вытесняются согласно выбранной политике.
 type Cache struct {
-	mu     sync.RWMutex               // RWMutex обеспечивает потокобезопасность для кэша
-	orders map[string]*database.Order // map для хранения заказов по их UID
+	shards []*shard // сегменты кэша
+
+	stop      chan struct{} // закрывается, чтобы остановить фоновую очистку устаревших заказов
+	closeOnce sync.Once
+}
+
+// Encoded содержит заказ, закодированный в JSON при записи в кэш, и его строгий ETag.
+// Срез JSON общий для всех читателей записи кэша, поэтому он не экспортируется:
+// его можно только записать в io.Writer через WriteTo или получить копию через Bytes.
+type Encoded struct {
+	json []byte // заказ в JSON, не изменяется после Encode
+	ETag string // строгий ETag в кавычках, хэш содержимого JSON
+}
+
+// Len возвращает длину JSON заказа в байтах
+func (e Encoded) Len() int {
+	return len(e.json)
+}
+
+// WriteTo записывает JSON заказа в w без копирования; по контракту io.Writer w не изменяет и не сохраняет срез
+func (e Encoded) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(e.json)
+	return int64(n), err
+}
+
+// Bytes возвращает копию JSON заказа
+func (e Encoded) Bytes() []byte {
+	return bytes.Clone(e.json)
+}
+
+// Encode кодирует заказ в JSON и вычисляет ETag.
+// Order состоит только из строк и чисел, поэтому json.Marshal не возвращает ошибку.
+func Encode(order *database.Order) Encoded {
+	data, _ := json.Marshal(order)
+	sum := sha256.Sum256(data)
+	return Encoded{json: data, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// EntryInfo описывает запись кэша в том виде, в котором она хранится
+type EntryInfo struct {
+	Order     json.RawMessage `json:"order"`                // копия JSON заказа, который отдается клиентам
+	ETag      string          `json:"etag"`                 // ETag заказа
+	Size      int64           `json:"size"`                 // приблизительный объем записи в байтах
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"` // срок жизни записи, если он ограничен
+}
+
+// Stats содержит статистику использования кэша
+type Stats struct {
+	Entries     int     `json:"entries"`     // количество заказов в кэше
+	Bytes       int64   `json:"bytes"`       // приблизительный объем заказов в байтах
+	Hits        int64   `json:"hits"`        // количество найденных заказов
+	Misses      int64   `json:"misses"`      // количество ненайденных заказов
+	Evictions   int64   `json:"evictions"`   // количество вытесненных при превышении лимитов заказов
+	Expirations int64   `json:"expirations"` // количество удаленных по истечении срока жизни заказов
+	HitRatio    float64 `json:"hit_ratio"`   // доля найденных заказов среди всех обращений
+}
+
+// New создает кэш заказов с ограничениями из opts.
+// Если задан срок жизни, запускается фоновая очистка, которую останавливает Close.
+func New(opts config.CacheConfig) *Cache {
+	n := max(opts.Shards, 1)
+	c := &Cache{
+		shards: make([]*shard, n),
+		stop:   make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i] = newShard(opts.Eviction, perShard(int64(opts.MaxEntries), n), perShard(opts.MaxBytes, n), opts.TTL)
+	}
+	if opts.TTL > 0 {
+		go c.expireLoop(cleanupInterval(opts.TTL)) // Запускаем фоновую очистку устаревших заказов
+	}
+	return c
+}
+
+// Close останавливает фоновую очистку устаревших заказов
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() { close(c.stop) })
+}
+
+// Get возвращает заказ из кэша по его UID
+func (c *Cache) Get(orderUID string) (*database.Order, bool) {
+	return c.shardFor(orderUID).get(orderUID)
+}
+
+// GetEncoded возвращает заказ в JSON вместе с ETag без повторного кодирования.
+// JSON не копируется, поэтому доступен только для записи через Encoded.WriteTo.
+func (c *Cache) GetEncoded(orderUID string) (Encoded, bool) {
+	return c.shardFor(orderUID).getEncoded(orderUID)
 }
 
-var cache *Cache // Переменная для хранения кэша заказов
+// Set сохраняет заказ в кэш и вытесняет заказы, если превышены лимиты
+func (c *Cache) Set(order *database.Order) {
+	c.shardFor(order.OrderUID).set(order)
+}
 
-// InitCache инициализирует кэш заказов
-func InitCache() {
-	cache = &Cache{
-		orders: make(map[string]*database.Order), // Инициализируем map для хранения заказов
+// Fill сохраняет заказ, прочитанный загрузкой кэша или при промахе, и сообщает, сохранен ли он.
+// Заказ, записанный через Set после начала чтения, новее копии из БД или снимка, поэтому Fill его не заменяет;
+// записи, сделанные через Fill, заменяются следующей загрузкой, например догрузкой изменений после снимка.
+func (c *Cache) Fill(order *database.Order) bool {
+	return c.shardFor(order.OrderUID).fill(order)
+}
+
+// Delete удаляет заказ из кэша и сообщает, был ли он в кэше
+func (c *Cache) Delete(orderUID string) bool {
+	return c.shardFor(orderUID).delete(orderUID)
+}
+
+// Purge удаляет все заказы из кэша, статистика обращений сохраняется
+func (c *Cache) Purge() {
+	for _, s := range c.shards {
+		s.purge()
 	}
 }
 
-// GetOrderFromCache возвращает заказ из кэша по его UID
-func GetOrderFromCache(orderUID string) (*database.Order, bool) {
-	cache.mu.RLock()                       // Блокируем кэш для чтения
-	defer cache.mu.RUnlock()               // Разблокируем кэш после выполнения функции
-	order, found := cache.orders[orderUID] // Ищем заказ в кэше
-	return order, found                    // Возвращаем найденный заказ и флаг его наличия
+// Len возвращает количество заказов в кэше
+func (c *Cache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.len()
+	}
+	return n
 }
 
-// SaveOrderToCache сохраняет заказ в кэш
-func SaveOrderToCache(order *database.Order) {
-	cache.mu.Lock()                      // Блокируем кэш для записи
-	defer cache.mu.Unlock()              // Разблокируем кэш после выполнения функции
-	cache.orders[order.OrderUID] = order // Сохраняем заказ в кэш
+// Range вызывает fn для каждого заказа с неистекшим сроком жизни, пока fn возвращает true.
+// Сегменты обходятся по очереди, fn вызывается без блокировки кэша и может обращаться к нему;
+// обращения через Range не учитываются в статистике.
+func (c *Cache) Range(fn func(order *database.Order) bool) {
+	for _, s := range c.shards {
+		for _, order := range s.snapshot(time.Now()) {
+			if !fn(order) {
+				return
+			}
+		}
+	}
 }
 
-// LoadCacheFromDB загружает кэш из базы данных
-func LoadCacheFromDB(db *sql.DB) error {
-	cache.mu.Lock()         // Блокируем кэш для записи
-	defer cache.mu.Unlock() // Разблокируем кэш после выполнения функции
+// Keys возвращает отсортированные UID заказов с неистекшим сроком жизни
+func (c *Cache) Keys() []string {
+	now := time.Now()
+	var keys []string
+	for _, s := range c.shards {
+		keys = s.appendKeys(keys, now)
+	}
+	slices.Sort(keys)
+	return keys
+}
 
-	orders, err := database.GetAllOrdersFromDB(db) // Получаем все заказы из базы данных
-	if err != nil {
-		return err // Возвращаем ошибку, если не удалось получить заказы из БД
+// Peek возвращает запись кэша, не учитывая обращение в статистике и политике вытеснения
+func (c *Cache) Peek(orderUID string) (EntryInfo, bool) {
+	return c.shardFor(orderUID).peek(orderUID)
+}
+
+// Stats возвращает статистику использования кэша, суммированную по сегментам
+func (c *Cache) Stats() Stats {
+	var stats Stats
+	for _, s := range c.shards {
+		s.addStats(&stats)
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// shardFor возвращает сегмент для заказа по FNV-1a хэшу его UID
+func (c *Cache) shardFor(orderUID string) *shard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	h := uint32(2166136261)
+	for i := 0; i < len(orderUID); i++ {
+		h ^= uint32(orderUID[i])
+		h *= 16777619
 	}
+	return c.shards[h%uint32(len(c.shards))]
+}
+
+// expireLoop периодически удаляет заказы с истекшим сроком жизни
+func (c *Cache) expireLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			for _, s := range c.shards {
+				s.expire(now)
+			}
+		}
+	}
+}
 
-	for _, order := range orders {
-		cache.orders[order.OrderUID] = order // Сохраняем каждый заказ в кэш
+// perShard делит лимит между n сегментами с округлением вверх, 0 означает отсутствие лимита
+func perShard(limit int64, n int) int64 {
+	if limit <= 0 {
+		return 0
 	}
+	return (limit + int64(n) - 1) / int64(n)
+}
 
-	return nil // Возвращаем nil, если загрузка прошла успешно
+// cleanupInterval возвращает период фоновой очистки: половина срока жизни, но не чаще раза в секунду и не реже раза в минуту
+func cleanupInterval(ttl time.Duration) time.Duration {
+	return min(max(ttl/2, time.Second), time.Minute)
 }
+
+// Проверяем, что Cache соответствует интерфейсу
+var _ OrderCache = (*Cache)(nil)
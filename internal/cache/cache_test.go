@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"bytes"                     // Импортируем пакет для сравнения JSON
+	"strconv"                   // Импортируем пакет для преобразования чисел в строки
+	"sync"                      // Импортируем пакет для синхронизации goroutine
+	"testing"                   // Импортируем пакет для тестов
+	"wb_test/internal/config"   // Импортируем пакет с настройками сервиса
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// testOrder возвращает заказ с заданным UID и номером трека, по которому тесты различают версии заказа
+func testOrder(uid, track string) *database.Order {
+	return &database.Order{
+		OrderUID:    uid,
+		TrackNumber: track,
+		Payment:     database.Payment{Amount: 1817},
+		Items:       []database.Item{{ChrtID: 1, TrackNumber: track, Name: "Mascaras"}},
+	}
+}
+
+// newTestCache создает кэш и останавливает его после теста
+func newTestCache(t testing.TB, opts config.CacheConfig) *Cache {
+	c := New(opts)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(c *Cache) // записи, сделанные до Fill
+		stored bool           // Fill сохранил заказ
+		want   string         // номер трека заказа в кэше после Fill
+	}{
+		{"пустой кэш", func(c *Cache) {}, true, "loaded"},
+		{"заказ записан через Set", func(c *Cache) { c.Set(testOrder("a", "ingested")) }, false, "ingested"},
+		{"заказ записан через Fill", func(c *Cache) { c.Fill(testOrder("a", "snapshot")) }, true, "loaded"},
+		{"заказ записан через Set и удален", func(c *Cache) { c.Set(testOrder("a", "ingested")); c.Delete("a") }, true, "loaded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t, config.CacheConfig{Shards: 4})
+			tt.before(c)
+			if stored := c.Fill(testOrder("a", "loaded")); stored != tt.stored {
+				t.Errorf("Fill() = %v, ожидается %v", stored, tt.stored)
+			}
+			got, ok := c.Get("a")
+			if !ok || got.TrackNumber != tt.want {
+				t.Errorf("Get() = %v, %v, ожидается заказ %s", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetReplacesFilled(t *testing.T) {
+	c := newTestCache(t, config.CacheConfig{})
+	c.Fill(testOrder("a", "loaded"))
+	c.Set(testOrder("a", "ingested"))
+	if got, _ := c.Get("a"); got.TrackNumber != "ingested" {
+		t.Errorf("Set не заменил загруженный заказ: %s", got.TrackNumber)
+	}
+	if c.Fill(testOrder("a", "stale")) {
+		t.Error("Fill заменил заказ, записанный через Set")
+	}
+}
+
+// TestConcurrentAccess обращается к одним и тем же заказам из нескольких goroutine.
+// Запускается с go test -race: детектор гонок проверяет, что читатели и писатели не обходят блокировки,
+// а проверки в читателях - что заказ всегда виден целиком.
+func TestConcurrentAccess(t *testing.T) {
+	for _, shards := range []int{1, 8} {
+		t.Run("shards="+strconv.Itoa(shards), func(t *testing.T) {
+			c := newTestCache(t, config.CacheConfig{Shards: shards, MaxEntries: 6})
+			keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+			const iterations = 500
+
+			var wg sync.WaitGroup
+			run := func(fn func(i int, key string)) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < iterations; i++ {
+						fn(i, keys[i%len(keys)])
+					}
+				}()
+			}
+			check := func(order *database.Order) {
+				if len(order.Items) != 1 || order.Items[0].TrackNumber != order.TrackNumber {
+					t.Errorf("заказ %s виден частично: %+v", order.OrderUID, order)
+				}
+			}
+			for w := 0; w < 4; w++ {
+				run(func(i int, key string) { c.Set(testOrder(key, "track-"+strconv.Itoa(i))) })
+				run(func(i int, key string) { c.Fill(testOrder(key, "loaded-"+strconv.Itoa(i))) })
+				run(func(i int, key string) {
+					if order, ok := c.Get(key); ok {
+						check(order)
+						order.Items[0].Name = "changed" // изменение копии не должно быть видно другим читателям
+					}
+				})
+				run(func(i int, key string) {
+					if encoded, ok := c.GetEncoded(key); ok && (encoded.Len() == 0 || encoded.ETag == "") {
+						t.Errorf("пустой JSON или ETag заказа %s", key)
+					}
+				})
+				run(func(i int, key string) {
+					if i%10 == 0 {
+						c.Delete(key)
+					}
+				})
+				run(func(i int, key string) {
+					if i%20 == 0 {
+						c.Range(func(order *database.Order) bool {
+							check(order)
+							order.TrackNumber = "changed"
+							return true
+						})
+						c.Keys()
+						c.Peek(key)
+						c.Stats()
+					}
+				})
+			}
+			wg.Wait()
+
+			if n, limit := c.Len(), int(perShard(6, shards))*shards; n > limit { // лимит делится между сегментами с округлением вверх
+				t.Errorf("Len() = %d, ожидается не больше %d", n, limit)
+			}
+			c.Range(func(order *database.Order) bool {
+				check(order)
+				if order.TrackNumber == "changed" || order.Items[0].Name == "changed" {
+					t.Errorf("изменение копии попало в кэш: %+v", order)
+				}
+				return true
+			})
+		})
+	}
+}
+
+// TestReturnsCopies проверяет, что изменение заказа, полученного из кэша или переданного в него, не меняет кэш
+func TestReturnsCopies(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Cache, stored *database.Order)
+	}{
+		{"заказ из Get", func(c *Cache, _ *database.Order) {
+			order, _ := c.Get("a")
+			order.TrackNumber = "changed"
+			order.Items[0].Name = "changed"
+			order.Items = append(order.Items, database.Item{Name: "extra"})
+		}},
+		{"заказ из Range", func(c *Cache, _ *database.Order) {
+			c.Range(func(order *database.Order) bool {
+				order.Delivery.Name = "changed"
+				order.Items[0].Name = "changed"
+				return true
+			})
+		}},
+		{"JSON из Peek", func(c *Cache, _ *database.Order) {
+			info, _ := c.Peek("a")
+			info.Order[0] = 'x'
+		}},
+		{"JSON из GetEncoded", func(c *Cache, _ *database.Order) {
+			encoded, _ := c.GetEncoded("a")
+			encoded.Bytes()[0] = 'x'
+		}},
+		{"заказ, переданный в Set", func(_ *Cache, stored *database.Order) {
+			stored.Payment.Amount = 0
+			stored.Items[0].Name = "changed"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t, config.CacheConfig{})
+			stored := testOrder("a", "track")
+			c.Set(stored)
+			before, _ := c.GetEncoded("a")
+			beforeJSON := before.Bytes()
+
+			tt.mutate(c, stored)
+
+			order, ok := c.Get("a")
+			if !ok {
+				t.Fatal("заказ не найден")
+			}
+			if order.TrackNumber != "track" || order.Delivery.Name != "" || order.Payment.Amount != 1817 ||
+				len(order.Items) != 1 || order.Items[0].Name != "Mascaras" {
+				t.Errorf("заказ в кэше изменился: %+v", order)
+			}
+			after, _ := c.GetEncoded("a")
+			if after.ETag != before.ETag {
+				t.Errorf("ETag изменился: %s, было %s", after.ETag, before.ETag)
+			}
+			var written bytes.Buffer
+			after.WriteTo(&written)
+			if !bytes.Equal(written.Bytes(), beforeJSON) {
+				t.Errorf("JSON заказа в кэше изменился: %s", written.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"container/heap"          // Импортируем пакет кучи для политики LFU
+	"container/list"          // Импортируем пакет двусвязного списка для политик LRU и TTL
+	"wb_test/internal/config" // Импортируем пакет с настройками сервиса
+)
+
+// evictionPolicy отслеживает обращения к ключам и выбирает ключ для вытеснения.
+// Методы вызываются под блокировкой кэша.
+type evictionPolicy interface {
+	add(key string)         // ключ добавлен в кэш
+	update(key string)      // значение существующего ключа перезаписано
+	touch(key string)       // значение ключа прочитано
+	remove(key string)      // ключ удален из кэша
+	victim() (string, bool) // ключ, который нужно вытеснить первым
+}
+
+// newEvictionPolicy создает политику вытеснения по ее имени из конфигурации
+func newEvictionPolicy(name config.EvictionPolicy) evictionPolicy {
+	switch name {
+	case config.EvictLFU:
+		return newLFU()
+	case config.EvictTTL:
+		return newListPolicy(false)
+	default:
+		return newListPolicy(true)
+	}
+}
+
+// listPolicy упорядочивает ключи в списке от самого свежего к самому старому.
+// Для LRU свежесть обновляется при чтении и записи, для TTL только при записи,
+// поэтому первым вытесняется заказ, срок жизни которого истекает раньше всех.
+type listPolicy struct {
+	order        *list.List
+	elements     map[string]*list.Element
+	touchOnReads bool
+}
+
+func newListPolicy(touchOnReads bool) *listPolicy {
+	return &listPolicy{order: list.New(), elements: make(map[string]*list.Element), touchOnReads: touchOnReads}
+}
+
+func (p *listPolicy) add(key string) {
+	p.elements[key] = p.order.PushFront(key)
+}
+
+func (p *listPolicy) update(key string) {
+	if el, ok := p.elements[key]; ok {
+		p.order.MoveToFront(el)
+	}
+}
+
+func (p *listPolicy) touch(key string) {
+	if p.touchOnReads {
+		p.update(key)
+	}
+}
+
+func (p *listPolicy) remove(key string) {
+	if el, ok := p.elements[key]; ok {
+		p.order.Remove(el)
+		delete(p.elements, key)
+	}
+}
+
+func (p *listPolicy) victim() (string, bool) {
+	el := p.order.Back()
+	if el == nil {
+		return "", false
+	}
+	return el.Value.(string), true
+}
+
+// lfuPolicy вытесняет ключ с наименьшим количеством обращений,
+// при равенстве - ключ, к которому обращались раньше
+type lfuPolicy struct {
+	nodes map[string]*lfuNode
+	heap  lfuHeap
+	clock uint64 // счетчик обращений для упорядочивания ключей с одинаковой частотой
+}
+
+type lfuNode struct {
+	key   string
+	freq  uint64
+	used  uint64 // значение clock при последнем обращении
+	index int    // позиция в куче
+}
+
+func newLFU() *lfuPolicy {
+	return &lfuPolicy{nodes: make(map[string]*lfuNode)}
+}
+
+func (p *lfuPolicy) add(key string) {
+	p.clock++
+	node := &lfuNode{key: key, freq: 1, used: p.clock}
+	p.nodes[key] = node
+	heap.Push(&p.heap, node)
+}
+
+func (p *lfuPolicy) update(key string) {
+	p.touch(key)
+}
+
+func (p *lfuPolicy) touch(key string) {
+	if node, ok := p.nodes[key]; ok {
+		p.clock++
+		node.freq++
+		node.used = p.clock
+		heap.Fix(&p.heap, node.index)
+	}
+}
+
+func (p *lfuPolicy) remove(key string) {
+	if node, ok := p.nodes[key]; ok {
+		heap.Remove(&p.heap, node.index)
+		delete(p.nodes, key)
+	}
+}
+
+func (p *lfuPolicy) victim() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	return p.heap[0].key, true
+}
+
+// lfuHeap реализует heap.Interface, в вершине находится ключ с наименьшей частотой
+type lfuHeap []*lfuNode
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].used < h[j].used
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	node := x.(*lfuNode)
+	node.index = len(*h)
+	*h = append(*h, node)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	node := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return node
+}
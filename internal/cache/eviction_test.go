@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"slices"                  // Импортируем пакет для сравнения срезов
+	"strings"                 // Импортируем пакет для разбора сценариев
+	"testing"                 // Импортируем пакет для тестов
+	"time"                    // Импортируем пакет для работы со временем
+	"wb_test/internal/config" // Импортируем пакет с настройками сервиса
+)
+
+// TestEvictionOrder проверяет, какие заказы остаются в кэше на 3 заказа после сценария обращений.
+// Сценарий: "set a" записывает заказ, "get a" читает его.
+func TestEvictionOrder(t *testing.T) {
+	tests := []struct {
+		policy config.EvictionPolicy
+		steps  string
+		want   []string
+	}{
+		{config.EvictLRU, "set a, set b, set c, set d", []string{"b", "c", "d"}},
+		{config.EvictLRU, "set a, set b, set c, get a, set d", []string{"a", "c", "d"}},
+		{config.EvictLRU, "set a, set b, set c, set a, set d", []string{"a", "c", "d"}},
+		{config.EvictLRU, "set a, set b, set c, get a, get b, set d", []string{"a", "b", "d"}},
+
+		{config.EvictLFU, "set a, set b, set c, set d", []string{"b", "c", "d"}},
+		{config.EvictLFU, "set a, set b, set c, get a, get a, get b, set d", []string{"a", "b", "d"}},
+		{config.EvictLFU, "set a, set b, set c, get c, get c, get b, set d, set e", []string{"b", "c", "e"}},
+		{config.EvictLFU, "set a, set b, set c, get a, get b, get c, set d", []string{"a", "b", "c"}}, // новый заказ читали реже всех
+
+		{config.EvictTTL, "set a, set b, set c, set d", []string{"b", "c", "d"}},
+		{config.EvictTTL, "set a, set b, set c, get a, get a, set d", []string{"b", "c", "d"}},
+		{config.EvictTTL, "set a, set b, set c, set a, set d", []string{"a", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+": "+tt.steps, func(t *testing.T) {
+			c := newTestCache(t, config.CacheConfig{Shards: 1, MaxEntries: 3, Eviction: tt.policy})
+			added := make(map[string]bool)
+			for _, step := range strings.Split(tt.steps, ", ") {
+				op, key, _ := strings.Cut(step, " ")
+				switch op {
+				case "set":
+					c.Set(testOrder(key, "track"))
+					added[key] = true
+				case "get":
+					if _, ok := c.Get(key); !ok {
+						t.Fatalf("%s: заказ вытеснен раньше времени", step)
+					}
+				}
+			}
+			if got := c.Keys(); !slices.Equal(got, tt.want) {
+				t.Errorf("в кэше %v, ожидается %v", got, tt.want)
+			}
+			if evictions, want := c.Stats().Evictions, int64(len(added)-len(tt.want)); evictions != want {
+				t.Errorf("Evictions = %d, ожидается %d", evictions, want)
+			}
+		})
+	}
+}
+
+// TestEvictionPolicies проверяет порядок выбора ключей для вытеснения без кэша
+func TestEvictionPolicies(t *testing.T) {
+	tests := []struct {
+		policy config.EvictionPolicy
+		touch  []string // ключи, прочитанные после добавления a, b, c
+		want   []string // порядок вытеснения
+	}{
+		{config.EvictLRU, nil, []string{"a", "b", "c"}},
+		{config.EvictLRU, []string{"a"}, []string{"b", "c", "a"}},
+		{config.EvictLRU, []string{"b", "a"}, []string{"c", "b", "a"}},
+		{config.EvictLFU, nil, []string{"a", "b", "c"}},
+		{config.EvictLFU, []string{"c", "c", "a"}, []string{"b", "a", "c"}},
+		{config.EvictLFU, []string{"a", "b"}, []string{"c", "a", "b"}},
+		{config.EvictTTL, []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			p := newEvictionPolicy(tt.policy)
+			for _, key := range []string{"a", "b", "c"} {
+				p.add(key)
+			}
+			for _, key := range tt.touch {
+				p.touch(key)
+			}
+			var got []string
+			for {
+				key, ok := p.victim()
+				if !ok {
+					break
+				}
+				got = append(got, key)
+				p.remove(key)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("порядок вытеснения %v, ожидается %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	order := testOrder("a", "track")
+	size := approxSize(order) + int64(Encode(order).Len()+len(Encode(order).ETag))
+	c := newTestCache(t, config.CacheConfig{Shards: 1, MaxBytes: 2*size + size/2})
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(testOrder(key, "track"))
+	}
+	if got := c.Keys(); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("в кэше %v, ожидается [b c]", got)
+	}
+	if stats := c.Stats(); stats.Bytes > 2*size+size/2 || stats.Evictions != 1 {
+		t.Errorf("Stats() = %+v, ожидается не больше %d байт и одно вытеснение", stats, 2*size+size/2)
+	}
+}
+
+func TestTTLExpiration(t *testing.T) {
+	c := newTestCache(t, config.CacheConfig{Shards: 1, TTL: 50 * time.Millisecond})
+	c.Set(testOrder("a", "track"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("заказ не найден до истечения срока жизни")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("заказ найден после истечения срока жизни")
+	}
+	if stats := c.Stats(); stats.Expirations != 1 || stats.Entries != 0 {
+		t.Errorf("Stats() = %+v, ожидается одно истечение срока и пустой кэш", stats)
+	}
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus" // Импортируем библиотеку метрик prometheus
+)
+
+// collector публикует статистику кэша в prometheus, статистика читается один раз за сбор метрик
+type collector struct {
+	cache OrderCache
+
+	entries, bytes, hits, misses, evictions, expirations *prometheus.Desc
+}
+
+// NewCollector создает сборщик метрик prometheus для кэша c
+func NewCollector(c OrderCache) prometheus.Collector {
+	return &collector{
+		cache:       c,
+		entries:     prometheus.NewDesc("orders_cache_entries", "Количество заказов в кэше.", nil, nil),
+		bytes:       prometheus.NewDesc("orders_cache_bytes", "Приблизительный объем заказов в кэше в байтах.", nil, nil),
+		hits:        prometheus.NewDesc("orders_cache_hits_total", "Количество найденных в кэше заказов.", nil, nil),
+		misses:      prometheus.NewDesc("orders_cache_misses_total", "Количество ненайденных в кэше заказов.", nil, nil),
+		evictions:   prometheus.NewDesc("orders_cache_evictions_total", "Количество заказов, вытесненных при превышении лимитов.", nil, nil),
+		expirations: prometheus.NewDesc("orders_cache_expirations_total", "Количество заказов, удаленных по истечении срока жизни.", nil, nil),
+	}
+}
+
+// Describe передает описания метрик кэша
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.bytes
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.expirations
+}
+
+// Collect передает текущие значения метрик кэша
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.Bytes))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.Expirations))
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"                   // Импортируем пакет контекста для запросов к базе данных
+	"database/sql"              // Импортируем пакет для работы с SQL базами данных
+	"log/slog"                  // Импортируем пакет структурированного логирования
+	"time"                      // Импортируем пакет для работы со временем
+	"wb_test/internal/config"   // Импортируем пакет с настройками сервиса
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// RefreshOnNotify подписывается на уведомления PostgreSQL об изменении заказов и обновляет их в кэше.
+// Измененный заказ перечитывается из базы данных, удаленный или непрочитанный - удаляется из кэша.
+// После разрыва соединения догружаются заказы, измененные с момента последнего уведомления,
+// и удаляются заказы, которых больше нет в базе данных.
+func RefreshOnNotify(c OrderCache, db *sql.DB, cfg config.DatabaseConfig) (*database.OrderListener, error) {
+	since := time.Now() // время, начиная с которого уведомления гарантированно обработаны
+
+	onChange := func(orderUID string) {
+		since = time.Now()
+		order, err := database.GetOrderFromDB(context.Background(), db, orderUID) // перечитываем измененный заказ
+		if err != nil {
+			slog.Warn("Не удалось перечитать заказ после уведомления, удаляем из кэша", "order_uid", orderUID, "error", err)
+			c.Delete(orderUID)
+			return
+		}
+		if order == nil {
+			c.Delete(orderUID) // заказ удален из базы данных
+			return
+		}
+		c.Set(order)
+	}
+
+	onReconnect := func() {
+		from := since.Add(-snapshotCatchUpMargin)
+		since = time.Now()
+		refreshed := 0
+		err := database.StreamOrdersUpdatedSince(db, from, func(order *database.Order) error {
+			c.Set(order)
+			refreshed++
+			return nil
+		})
+		if err != nil {
+			slog.Error("Не удалось догрузить заказы после восстановления соединения", "error", err)
+			return
+		}
+		deleted, err := dropDeleted(c, db, c.Keys()) // уведомления об удалении во время разрыва потеряны
+		if err != nil {
+			slog.Error("Не удалось проверить удаленные заказы после восстановления соединения", "error", err)
+			return
+		}
+		slog.Info("Соединение для уведомлений восстановлено", "refreshed", refreshed, "deleted", deleted)
+	}
+
+	return database.ListenOrderChanges(cfg, onChange, onReconnect)
+}
@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"sync"                      // Импортируем пакет для синхронизации goroutine
+	"time"                      // Импортируем пакет для работы со временем
+	"unsafe"                    // Импортируем пакет для получения размера структур
+	"wb_test/internal/config"   // Импортируем пакет с настройками сервиса
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// shard сегмент кэша со своей блокировкой, лимитами и политикой вытеснения
+type shard struct {
+	mu      sync.Mutex        // Mutex обеспечивает потокобезопасность; чтение тоже меняет порядок вытеснения, поэтому RWMutex не подходит
+	entries map[string]*entry // map для хранения заказов по их UID
+	policy  evictionPolicy    // политика вытеснения
+
+	maxEntries int64         // максимальное количество заказов, 0 - без ограничения
+	maxBytes   int64         // максимальный приблизительный объем, 0 - без ограничения
+	ttl        time.Duration // время жизни заказа, 0 - без ограничения
+	bytes      int64         // текущий приблизительный объем заказов
+
+	hits, misses, evictions, expirations int64 // счетчики для статистики
+}
+
+// entry хранит заказ вместе с его размером и сроком жизни
+type entry struct {
+	order     *database.Order // неизменяемая копия заказа; наружу отдаются только ее копии
+	encoded   Encoded         // заказ в JSON и его ETag, вычисленные при записи
+	size      int64
+	expiresAt time.Time // нулевое значение - срок жизни не ограничен
+	filled    bool      // заказ записан через fill и может быть заменен следующей загрузкой
+}
+
+func newShard(eviction config.EvictionPolicy, maxEntries, maxBytes int64, ttl time.Duration) *shard {
+	return &shard{
+		entries:    make(map[string]*entry), // Инициализируем map для хранения заказов
+		policy:     newEvictionPolicy(eviction),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+	}
+}
+
+func (s *shard) get(orderUID string) (*database.Order, bool) {
+	e, found := s.lookup(orderUID)
+	if !found {
+		return nil, false
+	}
+	return e.order.Clone(), true // Возвращаем копию заказа, чтобы вызывающий код не мог изменить кэш
+}
+
+func (s *shard) getEncoded(orderUID string) (Encoded, bool) {
+	e, found := s.lookup(orderUID)
+	if !found {
+		return Encoded{}, false
+	}
+	return e.encoded, true
+}
+
+// lookup ищет запись, удаляет ее при истекшем сроке жизни и учитывает обращение в статистике и политике вытеснения
+func (s *shard) lookup(orderUID string) (*entry, bool) {
+	s.mu.Lock()         // Блокируем сегмент
+	defer s.mu.Unlock() // Разблокируем сегмент после выполнения функции
+
+	e, found := s.entries[orderUID] // Ищем заказ в сегменте
+	if found && e.expired(time.Now()) {
+		s.remove(orderUID, e) // Удаляем заказ с истекшим сроком жизни
+		s.expirations++
+		found = false
+	}
+	if !found {
+		s.misses++
+		return nil, false
+	}
+	s.hits++
+	s.policy.touch(orderUID) // Отмечаем обращение к заказу для политики вытеснения
+	return e, true
+}
+
+func (s *shard) set(order *database.Order) {
+	s.store(order, false)
+}
+
+// fill сохраняет заказ, если по его ключу нет записи, сделанной через set, и сообщает, сохранен ли заказ
+func (s *shard) fill(order *database.Order) bool {
+	return s.store(order, true)
+}
+
+// store сохраняет заказ и вытесняет заказы, если превышены лимиты.
+// При filled заказ не заменяет неистекшую запись, сделанную через set: она новее прочитанной загрузкой копии.
+func (s *shard) store(order *database.Order, filled bool) bool {
+	snapshot := order.Clone() // Храним собственную копию, чтобы изменения у вызывающего кода не попадали в кэш
+	encoded := Encode(snapshot)
+	e := &entry{order: snapshot, encoded: encoded, size: approxSize(snapshot) + int64(encoded.Len()+len(encoded.ETag)), filled: filled} // JSON и размер считаем до блокировки
+	if s.ttl > 0 {
+		e.expiresAt = time.Now().Add(s.ttl)
+	}
+
+	s.mu.Lock()         // Блокируем сегмент для записи
+	defer s.mu.Unlock() // Разблокируем сегмент после выполнения функции
+
+	old, ok := s.entries[order.OrderUID]
+	if ok && filled && !old.filled && !old.expired(time.Now()) {
+		return false // заказ уже записан подписчиком или http обработчиком во время загрузки
+	}
+	if ok {
+		s.bytes -= old.size
+		s.policy.update(order.OrderUID)
+	} else {
+		s.policy.add(order.OrderUID)
+	}
+	s.entries[order.OrderUID] = e // Сохраняем заказ в сегмент
+	s.bytes += e.size
+
+	// Вытесняем заказы, пока не уложимся в лимиты; последний заказ оставляем, даже если он больше maxBytes
+	for len(s.entries) > 1 && s.overLimit() {
+		key, ok := s.policy.victim()
+		if !ok {
+			break
+		}
+		s.remove(key, s.entries[key])
+		s.evictions++
+	}
+	return true
+}
+
+func (s *shard) delete(orderUID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[orderUID]
+	if ok {
+		s.remove(orderUID, e)
+	}
+	return ok
+}
+
+func (s *shard) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, e := range s.entries {
+		s.remove(key, e)
+	}
+}
+
+func (s *shard) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// snapshot возвращает копии заказов сегмента с неистекшим сроком жизни
+func (s *shard) snapshot(now time.Time) []*database.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]*database.Order, 0, len(s.entries))
+	for _, e := range s.entries {
+		if !e.expired(now) {
+			orders = append(orders, e.order.Clone())
+		}
+	}
+	return orders
+}
+
+// appendKeys добавляет к keys UID заказов сегмента с неистекшим сроком жизни
+func (s *shard) appendKeys(keys []string, now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, e := range s.entries {
+		if !e.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// peek возвращает описание записи без изменения статистики и порядка вытеснения
+func (s *shard) peek(orderUID string) (EntryInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, found := s.entries[orderUID]
+	if !found || e.expired(time.Now()) {
+		return EntryInfo{}, false
+	}
+	info := EntryInfo{Order: e.encoded.Bytes(), ETag: e.encoded.ETag, Size: e.size} // копия JSON: EntryInfo отдается наружу целиком
+	if !e.expiresAt.IsZero() {
+		expiresAt := e.expiresAt
+		info.ExpiresAt = &expiresAt
+	}
+	return info, true
+}
+
+// addStats добавляет статистику сегмента к stats
+func (s *shard) addStats(stats *Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.Entries += len(s.entries)
+	stats.Bytes += s.bytes
+	stats.Hits += s.hits
+	stats.Misses += s.misses
+	stats.Evictions += s.evictions
+	stats.Expirations += s.expirations
+}
+
+// expire удаляет заказы с истекшим сроком жизни
+func (s *shard) expire(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, e := range s.entries {
+		if e.expired(now) {
+			s.remove(key, e)
+			s.expirations++
+		}
+	}
+}
+
+// remove удаляет запись из сегмента; вызывается под блокировкой
+func (s *shard) remove(key string, e *entry) {
+	delete(s.entries, key)
+	s.bytes -= e.size
+	s.policy.remove(key)
+}
+
+// overLimit сообщает, превышены ли лимиты сегмента; вызывается под блокировкой
+func (s *shard) overLimit() bool {
+	return (s.maxEntries > 0 && int64(len(s.entries)) > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// expired сообщает, истек ли срок жизни записи
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// approxSize оценивает объем памяти, занимаемый заказом
+func approxSize(o *database.Order) int64 {
+	size := int64(unsafe.Sizeof(*o)) + int64(unsafe.Sizeof(entry{}))
+	size += int64(len(o.OrderUID) + len(o.TrackNumber) + len(o.Entry) + len(o.Locale) + len(o.InternalSignature) +
+		len(o.CustomerID) + len(o.DeliveryService) + len(o.ShardKey) + len(o.DateCreated) + len(o.OofShard))
+	d := o.Delivery
+	size += int64(len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) + len(d.Address) + len(d.Region) + len(d.Email))
+	p := o.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) + len(p.Provider) + len(p.Bank))
+	for _, item := range o.Items {
+		size += int64(unsafe.Sizeof(item))
+		size += int64(len(item.TrackNumber) + len(item.Rid) + len(item.Name) + len(item.Size) + len(item.Brand))
+	}
+	return size + int64(2*len(o.OrderUID)) // ключ map и ключ в политике вытеснения
+}
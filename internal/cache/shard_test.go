@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"fmt"                       // Импортируем пакет для форматированного вывода
+	"math/rand/v2"              // Импортируем пакет для выбора случайных заказов
+	"strconv"                   // Импортируем пакет для преобразования чисел в строки
+	"sync/atomic"               // Импортируем пакет для атомарных счетчиков
+	"testing"                   // Импортируем пакет для тестов
+	"wb_test/internal/config"   // Импортируем пакет с настройками сервиса
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// benchKeys количество разных заказов, к которым обращаются бенчмарки
+const benchKeys = 10_000
+
+// BenchmarkCacheMixed сравнивает кэш из одного сегмента, то есть map под одной блокировкой, как до разделения
+// на сегменты, с кэшем из нескольких сегментов при разной доле чтений среди обращений из параллельных goroutine.
+// Запуск: go test ./internal/cache -run ^$ -bench CacheMixed -cpu 1,4,16
+func BenchmarkCacheMixed(b *testing.B) {
+	orders := make([]*database.Order, benchKeys)
+	for i := range orders {
+		orders[i] = testOrder("order-"+strconv.Itoa(i), "WBILMTESTTRACK")
+	}
+	for _, reads := range []int{100, 99, 90, 50, 10} {
+		for _, shards := range []int{1, 32} {
+			b.Run(fmt.Sprintf("reads=%d%%/shards=%d", reads, shards), func(b *testing.B) {
+				c := New(config.CacheConfig{Shards: shards})
+				defer c.Close()
+				for _, order := range orders {
+					c.Set(order)
+				}
+				var seed atomic.Uint64
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					r := rand.New(rand.NewPCG(seed.Add(1), 0)) // у каждой goroutine свой генератор, чтобы не делить его блокировку
+					for pb.Next() {
+						order := orders[r.IntN(benchKeys)]
+						if r.IntN(100) < reads {
+							c.GetEncoded(order.OrderUID)
+						} else {
+							c.Set(order)
+						}
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"bytes"                     // Импортируем пакет для работы с буферами
+	"crypto/sha256"             // Импортируем пакет для вычисления контрольной суммы
+	"database/sql"              // Импортируем пакет для работы с SQL базами данных
+	"encoding/binary"           // Импортируем пакет для записи чисел в бинарном виде
+	"encoding/gob"              // Импортируем пакет для бинарной сериализации
+	"errors"                    // Импортируем пакет для работы с ошибками
+	"fmt"                       // Импортируем пакет для форматированного вывода
+	"log/slog"                  // Импортируем пакет структурированного логирования
+	"os"                        // Импортируем пакет для работы с файлами
+	"path/filepath"             // Импортируем пакет для работы с путями
+	"sync"                      // Импортируем пакет для синхронизации goroutine
+	"time"                      // Импортируем пакет для работы со временем
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// Формат файла снимка:
+//
+//	magic (8 байт) | длина данных (uint64, big endian) | данные (gob Snapshot) | sha256 данных (32 байта)
+var snapshotMagic = [8]byte{'O', 'R', 'D', 'S', 'N', 'A', 'P', '1'}
+
+// snapshotCatchUpMargin запас при догрузке изменений после снимка: транзакции, начатые до снимка,
+// могли зафиксироваться позже и получить updated_at раньше времени снимка
+const snapshotCatchUpMargin = time.Minute
+
+// ErrSnapshotCorrupt возвращается, если файл снимка поврежден или имеет неизвестный формат
+var ErrSnapshotCorrupt = errors.New("снимок кэша поврежден")
+
+// Snapshot содержимое файла снимка кэша.
+// Заказы, сохраненные после снимка, догружаются из БД по updated_at, а LastSequence при загрузке
+// сравнивается с ingested_messages, чтобы не восстановить кэш из снимка другой базы данных.
+type Snapshot struct {
+	TakenAt      time.Time         // время сервера БД в момент начала снимка
+	LastSequence uint64            // номер последнего сообщения nats-streaming, заказ из которого сохранен в БД
+	Orders       []*database.Order // заказы из кэша
+}
+
+// WriteSnapshot атомарно записывает снимок в файл: данные пишутся во временный файл, который затем переименовывается
+func WriteSnapshot(path string, snapshot *Snapshot) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(snapshot); err != nil {
+		return fmt.Errorf("Ошибка кодирования снимка кэша: %v", err)
+	}
+	sum := sha256.Sum256(payload.Bytes())
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Ошибка создания файла снимка: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // После успешного переименования файла уже нет, ошибка игнорируется
+
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(payload.Len()))
+	for _, part := range [][]byte{snapshotMagic[:], length[:], payload.Bytes(), sum[:]} {
+		if _, err := tmp.Write(part); err != nil {
+			tmp.Close()
+			return fmt.Errorf("Ошибка записи снимка кэша: %v", err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Ошибка записи снимка кэша: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Ошибка записи снимка кэша: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("Ошибка сохранения снимка кэша: %v", err)
+	}
+	return nil
+}
+
+// ReadSnapshot читает снимок из файла и проверяет его контрольную сумму
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(snapshotMagic)+8+sha256.Size || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic[:]) {
+		return nil, ErrSnapshotCorrupt
+	}
+	data = data[len(snapshotMagic):]
+	length := binary.BigEndian.Uint64(data[:8])
+	data = data[8:]
+	if uint64(len(data)) != length+sha256.Size {
+		return nil, ErrSnapshotCorrupt
+	}
+	payload, sum := data[:length], data[length:]
+	if expected := sha256.Sum256(payload); !bytes.Equal(sum, expected[:]) {
+		return nil, ErrSnapshotCorrupt
+	}
+
+	var snapshot Snapshot
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	return &snapshot, nil
+}
+
+// LoadCacheFromSnapshot загружает кэш из файла снимка, догружает из базы данных заказы, измененные после снимка,
+// и удаляет из кэша заказы снимка, которых больше нет в базе данных.
+// Если файла нет, он поврежден или в ingested_messages нет сообщений из subject до LastSequence снимка
+// (снимок сделан для другой базы данных или записи о сообщениях удалены),
+// кэш загружается из базы данных целиком через LoadCacheFromDB.
+func (w *Warmup) LoadCacheFromSnapshot(c OrderCache, db *sql.DB, path, subject string) error {
+	snapshot, err := ReadSnapshot(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Info("Снимок кэша не найден, загружаем кэш из базы данных", "path", path)
+		} else {
+			slog.Warn("Не удалось прочитать снимок кэша, загружаем кэш из базы данных", "path", path, "error", err)
+		}
+		return w.LoadCacheFromDB(c, db)
+	}
+	lastSequence, err := database.LastIngestedSequence(db, subject)
+	if err != nil {
+		return err
+	}
+	if lastSequence < snapshot.LastSequence {
+		slog.Warn("Снимок кэша новее базы данных, загружаем кэш из базы данных",
+			"path", path, "snapshot_sequence", snapshot.LastSequence, "db_sequence", lastSequence)
+		return w.LoadCacheFromDB(c, db)
+	}
+
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.total.Store(int64(len(snapshot.Orders)))
+	for _, order := range snapshot.Orders {
+		c.Fill(order) // сохраняем каждый заказ из снимка, не заменяя записанные подписчиком
+		w.loaded.Add(1)
+	}
+	slog.Info("Кэш загружен из снимка", "taken_at", snapshot.TakenAt, "orders", len(snapshot.Orders),
+		"last_sequence", snapshot.LastSequence, "ingested_after", lastSequence-snapshot.LastSequence)
+
+	changed, deleted := 0, 0
+	err = database.StreamOrdersUpdatedSince(db, snapshot.TakenAt.Add(-snapshotCatchUpMargin), func(order *database.Order) error {
+		c.Fill(order) // заменяем заказы из снимка, измененные после него
+		changed++
+		w.total.Add(1)
+		w.loaded.Add(1)
+		return nil
+	})
+	if err == nil {
+		keys := make([]string, len(snapshot.Orders))
+		for i, order := range snapshot.Orders {
+			keys[i] = order.OrderUID
+		}
+		deleted, err = dropDeleted(c, db, keys) // удаления не оставляют строк с updated_at, проверяем заказы снимка по ключам
+	}
+	w.finish(err)
+	if err != nil {
+		return fmt.Errorf("Ошибка догрузки заказов после снимка: %v", err)
+	}
+	slog.Info("Догружены изменения после снимка", "changed", changed, "deleted", deleted)
+	return nil
+}
+
+// dropDeleted удаляет из кэша заказы из keys, которых нет в базе данных, и возвращает их количество.
+// Ключи проверяются страницами по database.OrderBatchSize.
+func dropDeleted(c OrderCache, db *sql.DB, keys []string) (int, error) {
+	deleted := 0
+	for start := 0; start < len(keys); start += database.OrderBatchSize {
+		batch := keys[start:min(start+database.OrderBatchSize, len(keys))]
+		existing, err := database.ExistingOrderUIDs(db, batch)
+		if err != nil {
+			return deleted, err
+		}
+		for _, key := range batch {
+			if !existing[key] && c.Delete(key) {
+				deleted++
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// Snapshotter периодически сохраняет содержимое кэша в файл снимка
+type Snapshotter struct {
+	path    string
+	subject string // канал nats-streaming, номер последнего сообщения из которого записывается в снимок
+	cache   OrderCache
+	db      *sql.DB
+
+	mu        sync.Mutex // не дает двум сохранениям писать файл одновременно
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSnapshotter создает объект для сохранения снимков кэша в path с номером последнего сообщения из subject
+func NewSnapshotter(path, subject string, c OrderCache, db *sql.DB) *Snapshotter {
+	return &Snapshotter{path: path, subject: subject, cache: c, db: db, stop: make(chan struct{})}
+}
+
+// Save записывает текущее содержимое кэша в файл снимка
+func (s *Snapshotter) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	takenAt, err := database.DBNow(s.db) // время снимка берем из БД, чтобы сравнивать его с updated_at без учета расхождения часов
+	if err != nil {
+		return err
+	}
+	lastSequence, err := database.LastIngestedSequence(s.db, s.subject) // номер берем до обхода кэша: заказы из сообщений до него уже в кэше
+	if err != nil {
+		return err
+	}
+	snapshot := &Snapshot{TakenAt: takenAt, LastSequence: lastSequence, Orders: make([]*database.Order, 0, s.cache.Len())}
+	s.cache.Range(func(order *database.Order) bool {
+		snapshot.Orders = append(snapshot.Orders, order)
+		return true
+	})
+
+	if err := WriteSnapshot(s.path, snapshot); err != nil {
+		return err
+	}
+	slog.Info("Снимок кэша сохранен", "path", s.path, "orders", len(snapshot.Orders), "last_sequence", snapshot.LastSequence)
+	return nil
+}
+
+// Run сохраняет снимок каждые interval, пока не вызван Close
+func (s *Snapshotter) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				slog.Error("Не удалось сохранить снимок кэша", "error", err)
+			}
+		}
+	}
+}
+
+// Close останавливает периодическое сохранение снимков
+func (s *Snapshotter) Close() {
+	s.closeOnce.Do(func() { close(s.stop) })
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"                    // Импортируем пакет для вызова методов хранилища
+	"errors"                     // Импортируем пакет для работы с ошибками
+	"os"                         // Импортируем пакет для работы с файлами
+	"path/filepath"              // Импортируем пакет для работы с путями
+	"testing"                    // Импортируем пакет для тестов
+	"time"                       // Импортируем пакет для работы со временем
+	"wb_test/internal/config"    // Импортируем пакет с настройками сервиса
+	"wb_test/internal/database"  // Импортируем локальный пакет для работы с базой данных
+	"wb_test/internal/ordertest" // Импортируем пакет с заказом для тестов
+)
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	want := &Snapshot{TakenAt: time.Now().UTC(), LastSequence: 42, Orders: []*database.Order{ordertest.ValidOrder("a")}}
+	if err := WriteSnapshot(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.TakenAt.Equal(want.TakenAt) || got.LastSequence != want.LastSequence || len(got.Orders) != 1 || got.Orders[0].OrderUID != "a" {
+		t.Errorf("прочитан снимок %+v, ожидается %+v", got, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff // портим байт данных, контрольная сумма перестает совпадать
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSnapshot(path); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("ошибка %v, ожидается %v", err, ErrSnapshotCorrupt)
+	}
+}
+
+// TestLoadCacheFromSnapshotSequence проверяет, что снимок с номером сообщения больше последнего в ingested_messages
+// не используется и кэш загружается из базы данных целиком
+func TestLoadCacheFromSnapshotSequence(t *testing.T) {
+	const subject = "snapshot-test"
+	db := openTestDB(t)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM ingested_messages WHERE subject = $1`, subject)
+		db.Exec(`DELETE FROM orders WHERE order_uid = 'snapshot-a'`)
+	})
+	repo := database.NewPostgresRepository(db, config.UpsertReplace)
+	err := repo.SaveIngested(context.Background(), ordertest.ValidOrder("snapshot-a"),
+		database.IngestedMessage{Subject: subject, Sequence: 5, ContentHash: "snapshot-hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		sequence  uint64
+		wantTrack string
+	}{
+		{"снимок не новее базы данных", 5, "SNAPSHOT"},
+		{"снимок новее базы данных", 6, "WBILMTESTTRACK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := ordertest.ValidOrder("snapshot-a")
+			order.TrackNumber = "SNAPSHOT"
+			path := filepath.Join(t.TempDir(), "cache.snapshot")
+			// время снимка в будущем: заказ из базы данных не догружается поверх заказа из снимка
+			snapshot := &Snapshot{TakenAt: time.Now().Add(time.Hour), LastSequence: tt.sequence, Orders: []*database.Order{order}}
+			if err := WriteSnapshot(path, snapshot); err != nil {
+				t.Fatal(err)
+			}
+
+			c := New(config.CacheConfig{})
+			defer c.Close()
+			var w Warmup
+			if err := w.LoadCacheFromSnapshot(c, db, path, subject); err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := c.Get("snapshot-a"); !ok || got.TrackNumber != tt.wantTrack {
+				t.Errorf("в кэше заказ %+v, ожидается трек %s", got, tt.wantTrack)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"database/sql"              // Импортируем пакет для работы с SQL базами данных
+	"errors"                    // Импортируем пакет для работы с ошибками
+	"log/slog"                  // Импортируем пакет структурированного логирования
+	"sync"                      // Импортируем пакет для синхронизации goroutine
+	"sync/atomic"               // Импортируем пакет для атомарных счетчиков
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// progressLogInterval количество заказов, после загрузки которых в лог пишется прогресс
+const progressLogInterval = 10_000
+
+// ErrWarmupRunning возвращается, если загрузка кэша уже выполняется
+var ErrWarmupRunning = errors.New("загрузка кэша уже выполняется")
+
+// WarmupProgress описывает ход загрузки кэша из базы данных
+type WarmupProgress struct {
+	Loaded  int64  `json:"loaded"`          // количество загруженных заказов
+	Total   int64  `json:"total"`           // количество заказов в базе данных на момент начала загрузки
+	Running bool   `json:"running"`         // загрузка выполняется
+	Ready   bool   `json:"ready"`           // загрузка завершилась успешно хотя бы один раз
+	Error   string `json:"error,omitempty"` // ошибка последней загрузки
+}
+
+// Warmup загружает кэш из базы данных и хранит ход загрузки.
+// Нулевое значение готово к использованию.
+type Warmup struct {
+	loaded  atomic.Int64
+	total   atomic.Int64
+	running atomic.Bool
+	ready   atomic.Bool
+
+	mu  sync.Mutex
+	err error
+}
+
+// LoadCacheFromDB загружает кэш из базы данных страницами по database.OrderBatchSize.
+// Кэш блокируется только на запись каждого заказа, поэтому чтение кэша во время загрузки не останавливается.
+// Заказы записываются через Fill: версия, которую подписчик сохранил во время загрузки, не заменяется прочитанной раньше копией.
+// Ход загрузки доступен через Status, после успешной загрузки Ready возвращает true.
+// Одновременно выполняется только одна загрузка, повторный вызов возвращает ErrWarmupRunning.
+func (w *Warmup) LoadCacheFromDB(c OrderCache, db *sql.DB) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.load(c, db)
+}
+
+// StartResync очищает кэш и запускает в фоне его повторную загрузку из базы данных,
+// чтобы убрать устаревшие и удаленные заказы. Пока загрузка не завершилась, промахи кэша читаются из базы данных.
+// Если загрузка уже выполняется, возвращает ErrWarmupRunning; ход загрузки доступен через Status.
+func (w *Warmup) StartResync(c OrderCache, db *sql.DB) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	c.Purge()
+	go func() {
+		if err := w.load(c, db); err != nil {
+			slog.Error("Не удалось повторно загрузить кэш из базы данных", "error", err)
+		}
+	}()
+	return nil
+}
+
+// load загружает заказы из базы данных в кэш; вызывается после start
+func (w *Warmup) load(c OrderCache, db *sql.DB) error {
+	total, err := database.CountOrders(db) // получаем количество заказов для отображения прогресса
+	if err != nil {
+		w.finish(err)
+		return err
+	}
+	w.total.Store(total)
+
+	err = database.StreamOrdersFromDB(db, func(order *database.Order) error {
+		c.Fill(order) // сохраняем заказ, если подписчик не записал более новую версию во время загрузки
+		loaded := w.loaded.Add(1)
+		if loaded%int64(progressLogInterval) == 0 {
+			slog.Info("Загрузка кэша", "loaded", loaded, "total", total)
+		}
+		return nil
+	})
+	w.finish(err)
+	if err != nil {
+		return err // Возвращаем ошибку, если не удалось получить заказы из БД
+	}
+
+	slog.Info("Кэш загружен", "orders", w.loaded.Load())
+	return nil // Возвращаем nil, если загрузка прошла успешно
+}
+
+// start отмечает начало загрузки и сбрасывает счетчики; возвращает ErrWarmupRunning, если загрузка уже идет
+func (w *Warmup) start() error {
+	if !w.running.CompareAndSwap(false, true) {
+		return ErrWarmupRunning
+	}
+	w.loaded.Store(0)
+	w.total.Store(0)
+	w.mu.Lock()
+	w.err = nil
+	w.mu.Unlock()
+	return nil
+}
+
+// finish отмечает окончание загрузки
+func (w *Warmup) finish(err error) {
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+	if err == nil {
+		w.ready.Store(true)
+	}
+	w.running.Store(false)
+}
+
+// Ready сообщает, завершилась ли загрузка кэша из базы данных
+func (w *Warmup) Ready() bool {
+	return w.ready.Load()
+}
+
+// Status возвращает текущий ход загрузки кэша
+func (w *Warmup) Status() WarmupProgress {
+	p := WarmupProgress{
+		Loaded:  w.loaded.Load(),
+		Total:   w.total.Load(),
+		Running: w.running.Load(),
+		Ready:   w.ready.Load(),
+	}
+	w.mu.Lock()
+	if w.err != nil {
+		p.Error = w.err.Error()
+	}
+	w.mu.Unlock()
+	return p
+}
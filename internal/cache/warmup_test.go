@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"database/sql"              // Импортируем пакет для работы с SQL базами данных
+	"os"                        // Импортируем пакет для чтения переменных окружения
+	"testing"                   // Импортируем пакет для тестов
+	"wb_test/internal/config"   // Импортируем пакет с настройками сервиса
+	"wb_test/internal/database" // Импортируем локальный пакет для работы с базой данных
+)
+
+// testDSNEnv переменная окружения со строкой подключения к тестовой базе данных.
+// Тесты и бенчмарки с базой данных пропускаются, если она не задана.
+const testDSNEnv = "ORDERS_TEST_DSN"
+
+// benchOrders количество заказов для бенчмарка загрузки кэша
+const benchOrders = 100_000
+
+// openTestDB подключается к тестовой базе данных и применяет миграции
+func openTestDB(tb testing.TB) *sql.DB {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		tb.Skipf("%s не задана, пропускаем проверку с базой данных", testDSNEnv)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	if _, err := database.MigrateUp(db); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// seedOrders записывает n заказов с одним товаром и UID с префиксом bench- и удаляет их после теста.
+// Заказы создаются запросами generate_series, чтобы подготовка не занимала больше времени, чем сама загрузка.
+func seedOrders(tb testing.TB, db *sql.DB, n int) {
+	queries := []string{
+		`INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
+		 SELECT 'bench-' || i, 'TRACK' || i, 'WBIL', 'en', '', 'customer' || i % 1000, 'meest', '9', 99, now() - i * interval '1 second', '1'
+		 FROM generate_series(1, $1) AS i ON CONFLICT DO NOTHING`,
+		`INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email)
+		 SELECT 'bench-' || i, 'Test Testov', '+9720000000', '2639809', 'Kiryat Mozkin', 'Ploshad Mira 15', 'Kraiot', 'test@gmail.com'
+		 FROM generate_series(1, $1) AS i ON CONFLICT DO NOTHING`,
+		`INSERT INTO payment (order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+		 SELECT 'bench-' || i, 'bench-' || i, '', 'USD', 'wbpay', 1817, 1637907727, 'alpha', 1500, 317, 0
+		 FROM generate_series(1, $1) AS i ON CONFLICT DO NOTHING`,
+		`INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
+		 SELECT 'bench-' || i, 9934930, 'TRACK' || i, 453, 'rid' || i, 'Mascaras', 30, '0', 317, 2389212, 'Vivienne Sabo', 202
+		 FROM generate_series(1, $1) AS i WHERE NOT EXISTS (SELECT 1 FROM items WHERE order_uid = 'bench-' || i)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query, n); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	tb.Cleanup(func() {
+		if _, err := db.Exec(`DELETE FROM orders WHERE order_uid LIKE 'bench-%'`); err != nil {
+			tb.Error(err)
+		}
+	})
+}
+
+// BenchmarkLoadCacheFromDB измеряет загрузку 100 тысяч заказов из базы данных в пустой кэш.
+// Запуск: ORDERS_TEST_DSN="host=localhost dbname=orders_test sslmode=disable" go test ./internal/cache -run ^$ -bench LoadCacheFromDB
+// Заказы, которые уже есть в тестовой базе данных, тоже загружаются и учитываются в результате.
+func BenchmarkLoadCacheFromDB(b *testing.B) {
+	db := openTestDB(b)
+	seedOrders(b, db, benchOrders)
+
+	var loaded int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := New(config.CacheConfig{Shards: 16})
+		var w Warmup
+		if err := w.LoadCacheFromDB(c, db); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		loaded = c.Len()
+		c.Close()
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(loaded), "orders")
+}
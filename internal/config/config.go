@@ -0,0 +1,485 @@
+package config
+
+import (
+	"errors"   // импорт пакета для работы с ошибками
+	"flag"     // импорт пакета для разбора флагов командной строки
+	"fmt"      // импорт пакета для форматированного вывода
+	"log/slog" // импорт пакета для разбора уровня логирования
+	"net/url"  // импорт пакета для разбора URL
+	"os"       // импорт пакета для работы с окружением и файлами
+	"strconv"  // импорт пакета для преобразования строк в числа
+	"strings"  // импорт пакета для работы со строками
+	"time"     // импорт пакета для работы со временем
+
+	"gopkg.in/yaml.v3" // импорт библиотеки для разбора YAML
+)
+
+// EnvPrefix префикс переменных окружения с настройками сервиса
+const EnvPrefix = "ORDERS_"
+
+// Config содержит все настройки сервиса.
+// Значения применяются в порядке: значения по умолчанию, YAML файл, переменные окружения, флаги командной строки.
+type Config struct {
+	HTTP     HTTPConfig     `yaml:"http"`
+	Database DatabaseConfig `yaml:"database"`
+	NATS     NATSConfig     `yaml:"nats"`
+	Cache    CacheConfig    `yaml:"cache"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // время на завершение запросов и обработки сообщений при остановке
+}
+
+// HTTPConfig настройки http сервера
+type HTTPConfig struct {
+	Addr         string        `yaml:"addr"`          // адрес, на котором слушает сервер
+	AdminToken   string        `yaml:"admin_token"`   // токен для /admin, пустое значение отключает администрирование
+	ReadyTimeout time.Duration `yaml:"ready_timeout"` // время на проверку зависимостей в /readyz и /status
+}
+
+// DatabaseConfig настройки подключения к PostgreSQL
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+
+	AutoMigrate  bool         `yaml:"auto_migrate"`  // применять миграции схемы при запуске сервиса
+	UpsertPolicy UpsertPolicy `yaml:"upsert_policy"` // поведение при повторном сохранении существующего заказа
+}
+
+// UpsertPolicy определяет, что делать при сохранении заказа, который уже есть в базе данных
+type UpsertPolicy string
+
+const (
+	UpsertReplace    UpsertPolicy = "replace"     // заменить все поля заказа и набор товаров
+	UpsertReject     UpsertPolicy = "reject"      // отклонить повторный заказ
+	UpsertKeepNewest UpsertPolicy = "keep-newest" // заменить, только если date_created нового заказа позже сохраненного
+)
+
+// Valid сообщает, является ли значение известной политикой
+func (p UpsertPolicy) Valid() bool {
+	switch p {
+	case UpsertReplace, UpsertReject, UpsertKeepNewest:
+		return true
+	}
+	return false
+}
+
+// DSN возвращает строку подключения к базе данных
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quote(c.Host), c.Port, quote(c.User), quote(c.Password), quote(c.Name), quote(c.SSLMode))
+}
+
+// CacheConfig настройки кэша заказов
+type CacheConfig struct {
+	WarmupBackground bool `yaml:"warmup_background"` // загружать кэш в фоне, не дожидаясь окончания загрузки перед запуском сервера
+
+	Shards     int            `yaml:"shards"`      // количество сегментов кэша со своими блокировками
+	MaxEntries int            `yaml:"max_entries"` // максимальное количество заказов в кэше, 0 - без ограничения
+	MaxBytes   int64          `yaml:"max_bytes"`   // приблизительный максимальный объем заказов в байтах, 0 - без ограничения
+	Eviction   EvictionPolicy `yaml:"eviction"`    // политика вытеснения при превышении лимитов
+	TTL        time.Duration  `yaml:"ttl"`         // время жизни заказа в кэше, 0 - без ограничения
+
+	RefreshOnNotify bool `yaml:"refresh_on_notify"` // обновлять кэш по уведомлениям PostgreSQL об изменении заказов
+
+	SnapshotPath     string        `yaml:"snapshot_path"`     // файл снимка кэша для быстрого перезапуска, пусто - снимки отключены
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"` // период сохранения снимка
+}
+
+// EvictionPolicy определяет, какой заказ вытесняется из кэша при превышении лимитов
+type EvictionPolicy string
+
+const (
+	EvictLRU EvictionPolicy = "lru" // вытесняется заказ, к которому дольше всего не обращались
+	EvictLFU EvictionPolicy = "lfu" // вытесняется заказ с наименьшим количеством обращений
+	EvictTTL EvictionPolicy = "ttl" // вытесняется заказ, срок жизни которого истекает раньше всех
+)
+
+// Valid сообщает, является ли значение известной политикой
+func (p EvictionPolicy) Valid() bool {
+	switch p {
+	case EvictLRU, EvictLFU, EvictTTL:
+		return true
+	}
+	return false
+}
+
+// LogConfig настройки логирования
+type LogConfig struct {
+	Level  string    `yaml:"level"`  // debug, info, warn или error; меняется без перезапуска через /admin/log-level
+	Format LogFormat `yaml:"format"` // формат записей
+}
+
+// LogFormat формат записей лога
+type LogFormat string
+
+const (
+	LogJSON LogFormat = "json" // одна JSON запись на строку
+	LogText LogFormat = "text" // пары ключ=значение для чтения в терминале
+)
+
+// Valid сообщает, является ли значение известным форматом
+func (f LogFormat) Valid() bool {
+	return f == LogJSON || f == LogText
+}
+
+// TracingConfig настройки трассировки OpenTelemetry
+type TracingConfig struct {
+	Exporter    TraceExporter `yaml:"exporter"`     // куда отправлять spans
+	Endpoint    string        `yaml:"endpoint"`     // адрес OTLP/HTTP коллектора, например localhost:4318
+	Insecure    bool          `yaml:"insecure"`     // подключаться к коллектору без TLS
+	SampleRatio float64       `yaml:"sample_ratio"` // доля новых трасс, которые записываются, от 0 до 1
+	ServiceName string        `yaml:"service_name"` // имя сервиса в трассах
+}
+
+// TraceExporter способ экспорта spans
+type TraceExporter string
+
+const (
+	TraceNone   TraceExporter = "none"   // трассировка отключена
+	TraceStdout TraceExporter = "stdout" // spans пишутся в stdout для локальной отладки
+	TraceOTLP   TraceExporter = "otlp"   // spans отправляются в OTLP/HTTP коллектор
+)
+
+// Valid сообщает, является ли значение известным способом экспорта
+func (e TraceExporter) Valid() bool {
+	switch e {
+	case TraceNone, TraceStdout, TraceOTLP:
+		return true
+	}
+	return false
+}
+
+// NATSConfig настройки подключения к nats-streaming
+type NATSConfig struct {
+	ClusterID         string `yaml:"cluster_id"`          // идентификатор кластера nats-streaming
+	ClientID          string `yaml:"client_id"`           // идентификатор клиента в nats-streaming
+	URL               string `yaml:"url"`                 // адрес сервера nats
+	Channel           string `yaml:"channel"`             // канал, в который публикуются заказы
+	Queue             string `yaml:"queue"`               // имя queue группы подписчиков
+	DurableName       string `yaml:"durable_name"`        // имя durable подписки
+	DeadLetterChannel string `yaml:"dead_letter_channel"` // канал для сообщений, которые не удалось обработать
+}
+
+// Default возвращает конфигурацию для локального запуска.
+// Пароль базы данных не задан: его нужно передать в файле, переменной ORDERS_DB_PASSWORD или флагом -db-password.
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Addr:         ":8000",
+			ReadyTimeout: 2 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogJSON,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TraceNone,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+			ServiceName: "order-service",
+		},
+		ShutdownTimeout: 30 * time.Second,
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "postgres",
+			Name:    "l0db",
+			SSLMode: "disable",
+
+			UpsertPolicy: UpsertReplace,
+		},
+		NATS: NATSConfig{
+			ClusterID:         "test-cluster",
+			ClientID:          "order-service",
+			URL:               "nats://localhost:4222",
+			Channel:           "channel-name",
+			Queue:             "order-service",
+			DurableName:       "my-durable",
+			DeadLetterChannel: "channel-name.dead-letter",
+		},
+		Cache: CacheConfig{
+			Shards:   32,
+			Eviction: EvictLRU,
+
+			RefreshOnNotify:  true,
+			SnapshotInterval: 5 * time.Minute,
+		},
+	}
+}
+
+// Load собирает конфигурацию из файла, переменных окружения и флагов.
+// Флаги регистрируются в fs, поэтому вызывающий код может добавить в него свои флаги до вызова Load.
+// Путь к файлу задается флагом -config или переменной ORDERS_CONFIG.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	configPath := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "путь к YAML файлу конфигурации")
+	flagValues := make(map[string]*string, len(settings))
+	for name, s := range settings {
+		flagValues[name] = fs.String(name, "", s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Значения из файла
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения файла конфигурации: %v", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("Ошибка разбора файла конфигурации %s: %v", *configPath, err)
+		}
+	}
+
+	// Значения из переменных окружения
+	for _, s := range settings {
+		env := envName(s.name)
+		if value, ok := os.LookupEnv(env); ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("Некорректное значение %s: %v", env, err)
+			}
+		}
+	}
+
+	// Значения из флагов, заданных явно
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := settings[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := s.set(*flagValues[f.Name]); err != nil {
+			flagErr = fmt.Errorf("Некорректное значение флага -%s: %v", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate проверяет, что конфигурация пригодна для запуска сервиса
+func (c *Config) Validate() error {
+	var errs []error
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr не задан"))
+	}
+	if c.HTTP.ReadyTimeout <= 0 {
+		errs = append(errs, errors.New("http.ready_timeout должен быть положительным"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(c.Log.Level))); err != nil {
+		errs = append(errs, fmt.Errorf("log.level должен быть debug, info, warn или error: %q", c.Log.Level))
+	}
+	if !c.Log.Format.Valid() {
+		errs = append(errs, fmt.Errorf("log.format должен быть json или text: %q", c.Log.Format))
+	}
+	if !c.Tracing.Exporter.Valid() {
+		errs = append(errs, fmt.Errorf("tracing.exporter должен быть none, stdout или otlp: %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.Exporter == TraceOTLP && c.Tracing.Endpoint == "" {
+		errs = append(errs, errors.New("для tracing.exporter=otlp нужно задать tracing.endpoint"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio должен быть от 0 до 1: %v", c.Tracing.SampleRatio))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout должен быть положительным"))
+	}
+	if c.Database.Host == "" {
+		errs = append(errs, errors.New("database.host не задан"))
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port вне диапазона: %d", c.Database.Port))
+	}
+	if c.Database.User == "" {
+		errs = append(errs, errors.New("database.user не задан"))
+	}
+	if c.Database.Password == "" {
+		errs = append(errs, errors.New("database.password не задан: укажите его в файле, ORDERS_DB_PASSWORD или -db-password"))
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, errors.New("database.name не задан"))
+	}
+	if !c.Database.UpsertPolicy.Valid() {
+		errs = append(errs, fmt.Errorf("database.upsert_policy должен быть replace, reject или keep-newest: %q", c.Database.UpsertPolicy))
+	}
+	if c.NATS.ClusterID == "" || c.NATS.ClientID == "" {
+		errs = append(errs, errors.New("nats.cluster_id и nats.client_id обязательны"))
+	}
+	if u, err := url.Parse(c.NATS.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("nats.url некорректен: %q", c.NATS.URL))
+	}
+	if c.NATS.Channel == "" || c.NATS.DeadLetterChannel == "" {
+		errs = append(errs, errors.New("nats.channel и nats.dead_letter_channel обязательны"))
+	}
+	if c.NATS.Channel == c.NATS.DeadLetterChannel {
+		errs = append(errs, errors.New("nats.dead_letter_channel должен отличаться от nats.channel"))
+	}
+	if c.NATS.Queue == "" || c.NATS.DurableName == "" {
+		errs = append(errs, errors.New("nats.queue и nats.durable_name обязательны"))
+	}
+	if c.Cache.Shards < 1 || c.Cache.Shards > 4096 {
+		errs = append(errs, fmt.Errorf("cache.shards должен быть от 1 до 4096: %d", c.Cache.Shards))
+	}
+	if c.Cache.MaxEntries < 0 || c.Cache.MaxBytes < 0 || c.Cache.TTL < 0 {
+		errs = append(errs, errors.New("cache.max_entries, cache.max_bytes и cache.ttl не могут быть отрицательными"))
+	}
+	if c.Cache.SnapshotPath != "" && c.Cache.SnapshotInterval <= 0 {
+		errs = append(errs, errors.New("cache.snapshot_interval должен быть положительным"))
+	}
+	if !c.Cache.Eviction.Valid() {
+		errs = append(errs, fmt.Errorf("cache.eviction должен быть lru, lfu или ttl: %q", c.Cache.Eviction))
+	}
+	if c.Cache.Eviction == EvictTTL && c.Cache.TTL == 0 {
+		errs = append(errs, errors.New("для cache.eviction=ttl нужно задать cache.ttl"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Некорректная конфигурация: %v", errors.Join(errs...))
+	}
+	return nil
+}
+
+// setting связывает поле конфигурации с флагом и переменной окружения
+type setting struct {
+	name  string                   // имя флага, например db-host
+	usage string                   // описание флага
+	set   func(value string) error // записывает значение в поле конфигурации
+}
+
+// envName возвращает имя переменной окружения для флага, например ORDERS_DB_HOST для db-host
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// settings перечисляет настройки, которые можно переопределить через окружение и флаги, по имени флага
+func (c *Config) settings() map[string]setting {
+	list := []setting{
+		stringSetting("http-addr", "адрес http сервера", &c.HTTP.Addr),
+		stringSetting("http-admin-token", "токен для /admin, пустое значение отключает администрирование", &c.HTTP.AdminToken),
+		durationSetting("http-ready-timeout", "время на проверку зависимостей в /readyz и /status", &c.HTTP.ReadyTimeout),
+		stringSetting("log-level", "уровень логирования: debug, info, warn или error", &c.Log.Level),
+		stringSetting("log-format", "формат логов: json или text", (*string)(&c.Log.Format)),
+		stringSetting("tracing-exporter", "экспорт трасс: none, stdout или otlp", (*string)(&c.Tracing.Exporter)),
+		stringSetting("tracing-endpoint", "адрес OTLP/HTTP коллектора", &c.Tracing.Endpoint),
+		boolSetting("tracing-insecure", "подключаться к коллектору без TLS (true/false)", &c.Tracing.Insecure),
+		float64Setting("tracing-sample-ratio", "доля записываемых трасс от 0 до 1", &c.Tracing.SampleRatio),
+		stringSetting("tracing-service-name", "имя сервиса в трассах", &c.Tracing.ServiceName),
+		durationSetting("shutdown-timeout", "время на завершение запросов и обработки сообщений при остановке", &c.ShutdownTimeout),
+		stringSetting("db-host", "хост PostgreSQL", &c.Database.Host),
+		intSetting("db-port", "порт PostgreSQL", &c.Database.Port),
+		stringSetting("db-user", "пользователь PostgreSQL", &c.Database.User),
+		stringSetting("db-password", "пароль PostgreSQL", &c.Database.Password),
+		stringSetting("db-name", "имя базы данных", &c.Database.Name),
+		stringSetting("db-sslmode", "режим sslmode подключения к PostgreSQL", &c.Database.SSLMode),
+		stringSetting("db-upsert-policy", "политика повторного сохранения заказа: replace, reject или keep-newest", (*string)(&c.Database.UpsertPolicy)),
+		boolSetting("db-auto-migrate", "применять миграции схемы при запуске (true/false)", &c.Database.AutoMigrate),
+		boolSetting("cache-warmup-background", "загружать кэш в фоне после запуска сервера (true/false)", &c.Cache.WarmupBackground),
+		intSetting("cache-shards", "количество сегментов кэша", &c.Cache.Shards),
+		intSetting("cache-max-entries", "максимальное количество заказов в кэше, 0 - без ограничения", &c.Cache.MaxEntries),
+		int64Setting("cache-max-bytes", "приблизительный максимальный объем кэша в байтах, 0 - без ограничения", &c.Cache.MaxBytes),
+		stringSetting("cache-eviction", "политика вытеснения кэша: lru, lfu или ttl", (*string)(&c.Cache.Eviction)),
+		durationSetting("cache-ttl", "время жизни заказа в кэше, например 30m, 0 - без ограничения", &c.Cache.TTL),
+		boolSetting("cache-refresh-on-notify", "обновлять кэш по уведомлениям PostgreSQL (true/false)", &c.Cache.RefreshOnNotify),
+		stringSetting("cache-snapshot-path", "файл снимка кэша, пусто - снимки отключены", &c.Cache.SnapshotPath),
+		durationSetting("cache-snapshot-interval", "период сохранения снимка кэша", &c.Cache.SnapshotInterval),
+		stringSetting("nats-cluster-id", "идентификатор кластера nats-streaming", &c.NATS.ClusterID),
+		stringSetting("nats-client-id", "идентификатор клиента nats-streaming", &c.NATS.ClientID),
+		stringSetting("nats-url", "адрес сервера nats", &c.NATS.URL),
+		stringSetting("nats-channel", "канал с заказами", &c.NATS.Channel),
+		stringSetting("nats-queue", "имя queue группы подписчиков", &c.NATS.Queue),
+		stringSetting("nats-durable-name", "имя durable подписки", &c.NATS.DurableName),
+		stringSetting("nats-dead-letter-channel", "канал для необработанных сообщений", &c.NATS.DeadLetterChannel),
+	}
+	settings := make(map[string]setting, len(list))
+	for _, s := range list {
+		settings[s.name] = s
+	}
+	return settings
+}
+
+func stringSetting(name, usage string, field *string) setting {
+	return setting{name: name, usage: usage, set: func(value string) error {
+		*field = value
+		return nil
+	}}
+}
+
+func intSetting(name, usage string, field *int) setting {
+	return setting{name: name, usage: usage, set: func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field = n
+		return nil
+	}}
+}
+
+func int64Setting(name, usage string, field *int64) setting {
+	return setting{name: name, usage: usage, set: func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field = n
+		return nil
+	}}
+}
+
+func float64Setting(name, usage string, field *float64) setting {
+	return setting{name: name, usage: usage, set: func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*field = f
+		return nil
+	}}
+}
+
+func durationSetting(name, usage string, field *time.Duration) setting {
+	return setting{name: name, usage: usage, set: func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = d
+		return nil
+	}}
+}
+
+func boolSetting(name, usage string, field *bool) setting {
+	return setting{name: name, usage: usage, set: func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field = b
+		return nil
+	}}
+}
+
+// quote экранирует значение для строки подключения lib/pq
+func quote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
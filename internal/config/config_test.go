@@ -0,0 +1,145 @@
+package config
+
+import (
+	"flag"          // импорт пакета для разбора флагов командной строки
+	"os"            // импорт пакета для работы с окружением и файлами
+	"path/filepath" // импорт пакета для пути к файлу конфигурации
+	"strings"       // импорт пакета для работы со строками
+	"testing"       // импорт пакета для тестов
+)
+
+// load вызывает Load с новым набором флагов; пустые yaml и env не задают файл и переменные окружения
+func load(t *testing.T, yaml string, env map[string]string, args ...string) (*Config, error) {
+	t.Setenv(EnvPrefix+"CONFIG", "")
+	if yaml != "" {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(EnvPrefix+"CONFIG", path)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	return Load(flag.NewFlagSet("test", flag.ContinueOnError), args)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const yaml = "database:\n  host: yaml-host\n  port: 6000\n"
+	env := map[string]string{"ORDERS_DB_HOST": "env-host"}
+	tests := []struct {
+		name     string
+		yaml     string
+		env      map[string]string
+		args     []string
+		wantHost string
+		wantPort int
+	}{
+		{"значения по умолчанию", "", nil, nil, "localhost", 5432},
+		{"файл", yaml, nil, nil, "yaml-host", 6000},
+		{"окружение поверх файла", yaml, env, nil, "env-host", 6000},
+		{"флаг поверх окружения", yaml, env, []string{"-db-host", "flag-host"}, "flag-host", 6000},
+		{"флаг поверх файла", yaml, nil, []string{"-db-port", "7000"}, "yaml-host", 7000},
+		{"флаг без файла и окружения", "", nil, []string{"-db-host", "flag-host"}, "flag-host", 5432},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-db-password", "secret"}, tt.args...)
+			cfg, err := load(t, tt.yaml, tt.env, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Database.Host != tt.wantHost || cfg.Database.Port != tt.wantPort {
+				t.Errorf("host=%s port=%d, ожидается host=%s port=%d", cfg.Database.Host, cfg.Database.Port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestLoadPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"не задан", "", nil, nil, "", true},
+		{"в файле", "database:\n  password: from-file\n", nil, nil, "from-file", false},
+		{"в окружении", "", map[string]string{"ORDERS_DB_PASSWORD": "from-env"}, nil, "from-env", false},
+		{"во флаге", "", nil, []string{"-db-password", "from-flag"}, "from-flag", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, tt.yaml, tt.env, tt.args...)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "database.password") {
+					t.Errorf("ошибка %v, ожидается ошибка database.password", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Database.Password != tt.want {
+				t.Errorf("пароль %q, ожидается %q", cfg.Database.Password, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidValue(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string // часть текста ошибки
+	}{
+		{"окружение", map[string]string{"ORDERS_DB_PORT": "port"}, nil, "ORDERS_DB_PORT"},
+		{"флаг", nil, []string{"-cache-ttl", "long"}, "-cache-ttl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-db-password", "secret"}, tt.args...)
+			if _, err := load(t, "", tt.env, args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ошибка %v, ожидается ошибка %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Config)
+		want   string // поле в тексте ошибки, пусто - конфигурация корректна
+	}{
+		{"корректная конфигурация", func(c *Config) {}, ""},
+		{"нет пароля", func(c *Config) { c.Database.Password = "" }, "database.password"},
+		{"неизвестная политика сохранения", func(c *Config) { c.Database.UpsertPolicy = "merge" }, "database.upsert_policy"},
+		{"неизвестная политика вытеснения", func(c *Config) { c.Cache.Eviction = "random" }, "cache.eviction"},
+		{"ttl без срока жизни", func(c *Config) { c.Cache.Eviction = EvictTTL }, "cache.ttl"},
+		{"неизвестный уровень логирования", func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+		{"уровень логирования в любом регистре", func(c *Config) { c.Log.Level = "DEBUG" }, ""},
+		{"неизвестный формат логов", func(c *Config) { c.Log.Format = "xml" }, "log.format"},
+		{"dead-letter в канал заказов", func(c *Config) { c.NATS.DeadLetterChannel = c.NATS.Channel }, "nats.dead_letter_channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.Database.Password = "secret"
+			tt.mutate(c)
+			err := c.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, ожидается nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, ожидается ошибка %s", err, tt.want)
+			}
+		})
+	}
+}
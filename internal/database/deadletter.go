@@ -0,0 +1,97 @@
+package database
+
+import (
+	"database/sql" // импорт стандартного пакета для работы с базой данных
+	"fmt"          // импорт пакета для форматированного вывода
+	"time"         // импорт пакета для работы со временем
+)
+
+// Этапы обработки сообщения, на которых оно может попасть в dead-letter
+const (
+	StageDecode   = "decode"   // не удалось декодировать JSON сообщения
+	StageValidate = "validate" // заказ не прошел проверку
+	StageSave     = "save"     // не удалось сохранить заказ в базу данных
+)
+
+// Структура для хранения сообщения, которое не удалось обработать
+type DeadLetter struct {
+	ID         int64      `json:"id"`
+	Subject    string     `json:"subject"`     // канал, из которого пришло сообщение
+	Sequence   uint64     `json:"sequence"`    // порядковый номер сообщения в канале
+	Stage      string     `json:"stage"`       // этап, на котором произошла ошибка
+	Error      string     `json:"error"`       // текст ошибки
+	Payload    string     `json:"payload"`     // исходное содержимое сообщения
+	CreatedAt  time.Time  `json:"created_at"`  // время попадания в dead-letter
+	ReplayedAt *time.Time `json:"replayed_at"` // время повторной отправки, nil если сообщение не отправлялось
+}
+
+// Функция для сохранения сообщения в таблицу dead_letters
+func SaveDeadLetter(db *sql.DB, dl *DeadLetter) error {
+	err := db.QueryRow(`INSERT INTO dead_letters (subject, sequence, stage, error, payload, created_at)
+	                    VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		dl.Subject, int64(dl.Sequence), dl.Stage, dl.Error, []byte(dl.Payload), dl.CreatedAt).Scan(&dl.ID)
+	if err != nil {
+		return fmt.Errorf("Ошибка сохранения dead letter: %v", err) // возвращаем ошибку в случае неудачного ввода данных
+	}
+	return nil
+}
+
+// Функция для получения списка сообщений из dead_letters, начиная с самых новых
+func GetDeadLetters(db *sql.DB, limit, offset int) ([]*DeadLetter, error) {
+	rows, err := db.Query(`SELECT id, subject, sequence, stage, error, payload, created_at, replayed_at
+	                       FROM dead_letters ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка получения dead letters: %v", err) // возвращаем ошибку в случае неудачного запроса
+	}
+	defer rows.Close()
+
+	deadLetters := make([]*DeadLetter, 0)
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, dl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Ошибка итерации по строкам dead_letters: %v", err)
+	}
+	return deadLetters, nil
+}
+
+// Функция для получения сообщения из dead_letters по его ID
+func GetDeadLetter(db *sql.DB, id int64) (*DeadLetter, error) {
+	row := db.QueryRow(`SELECT id, subject, sequence, stage, error, payload, created_at, replayed_at
+	                    FROM dead_letters WHERE id = $1`, id)
+	dl, err := scanDeadLetter(row)
+	if err == sql.ErrNoRows {
+		return nil, nil // если сообщение не найдено, возвращаем nil
+	}
+	return dl, err
+}
+
+// Функция для отметки сообщения как повторно отправленного
+func MarkDeadLetterReplayed(db *sql.DB, id int64) error {
+	_, err := db.Exec(`UPDATE dead_letters SET replayed_at = now() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("Ошибка обновления dead letter: %v", err)
+	}
+	return nil
+}
+
+// scanDeadLetter сканирует строку таблицы dead_letters
+func scanDeadLetter(row interface{ Scan(...any) error }) (*DeadLetter, error) {
+	var dl DeadLetter
+	var sequence int64
+	var payload []byte
+	err := row.Scan(&dl.ID, &dl.Subject, &sequence, &dl.Stage, &dl.Error, &payload, &dl.CreatedAt, &dl.ReplayedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка сканирования dead letter: %v", err)
+	}
+	dl.Sequence = uint64(sequence)
+	dl.Payload = string(payload)
+	return &dl, nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"                 // импорт пакета для передачи контекста сообщения в запросы
+	"database/sql"            // импорт стандартного пакета для работы с базой данных
+	"errors"                  // импорт пакета для работы с ошибками
+	"fmt"                     // импорт пакета для форматированного вывода
+	"time"                    // импорт пакета для работы со временем
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+)
+
+// ErrDuplicateMessage возвращается, если сообщение с тем же каналом, номером и содержимым уже было обработано
+var ErrDuplicateMessage = errors.New("сообщение уже обработано")
+
+// Структура для хранения ключа идемпотентности сообщения из nats-streaming
+type IngestedMessage struct {
+	Subject     string // канал, из которого пришло сообщение
+	Sequence    uint64 // порядковый номер сообщения в канале
+	ContentHash string // хэш содержимого сообщения
+}
+
+// Функция для сохранения заказа, полученного из nats-streaming.
+// Сообщение записывается в ingested_messages в той же транзакции, что и заказ,
+// поэтому повторная доставка того же сообщения возвращает ErrDuplicateMessage без изменения данных.
+// Дубликатом считается только сообщение с тем же каналом, номером и хэшем содержимого: после сброса хранилища
+// nats-streaming или пересоздания канала номера повторяются, и сообщение с другим содержимым под старым номером
+// сохраняется как новое, а запись о прежнем сообщении заменяется.
+func SaveIngestedOrder(ctx context.Context, db *sql.DB, order *Order, policy config.UpsertPolicy, msg IngestedMessage) (err error) {
+	defer observeQuery(ctx, "save_ingested_order", time.Now(), &err)
+	ctx, span := startOperation(ctx, "SaveIngestedOrder", order.OrderUID)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := db.BeginTx(ctx, nil) // начинаем транзакцию
+	if err != nil {
+		return fmt.Errorf("Ошибка при запуске транзакции: %v", err)
+	}
+
+	res, err := execTx(ctx, tx, "INSERT ingested_messages", `INSERT INTO ingested_messages (subject, sequence, content_hash, order_uid)
+	                     VALUES ($1, $2, $3, $4)
+	                     ON CONFLICT (subject, sequence) DO UPDATE
+	                     SET content_hash = EXCLUDED.content_hash, order_uid = EXCLUDED.order_uid, ingested_at = now()
+	                     WHERE ingested_messages.content_hash <> EXCLUDED.content_hash`,
+		msg.Subject, int64(msg.Sequence), msg.ContentHash, order.OrderUID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Ошибка при вводе ingested_messages: %v", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Ошибка при вводе ingested_messages: %v", err)
+	}
+	if affected == 0 { // сообщение с тем же номером и содержимым уже записано: это повторная доставка
+		tx.Rollback()
+		return ErrDuplicateMessage
+	}
+
+	err = saveOrderTx(ctx, tx, order, policy) // сохраняем заказ в той же транзакции
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = commitTx(ctx, tx) // подтверждаем транзакцию
+	if err != nil {
+		return fmt.Errorf("Ошибка при совершении транзакции: %v", err)
+	}
+	return nil
+}
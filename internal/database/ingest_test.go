@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"                 // импорт пакета для передачи контекста сообщения в запросы
+	"errors"                  // импорт пакета для работы с ошибками
+	"os"                      // импорт пакета для чтения переменных окружения
+	"testing"                 // импорт пакета для тестов
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+)
+
+// TestSaveIngested проверяет, что дубликатом считается только сообщение с тем же номером и содержимым,
+// а сообщение с номером, повторившимся после сброса nats-streaming, сохраняется как новое.
+// Хранилище в памяти проверяется всегда, PostgreSQL - если задана ORDERS_TEST_DSN.
+func TestSaveIngested(t *testing.T) {
+	const subject = "ingest-test"
+	repos := map[string]interface {
+		IngestRepository
+		OrderRepository
+	}{"memory": NewMemoryRepository(config.UpsertReject)}
+	if os.Getenv(testDSNEnv) != "" {
+		db := openTestDB(t)
+		t.Cleanup(func() {
+			db.Exec(`DELETE FROM ingested_messages WHERE subject = $1`, subject)
+			db.Exec(`DELETE FROM orders WHERE order_uid LIKE 'ingest-%'`)
+		})
+		repos["postgres"] = NewPostgresRepository(db, config.UpsertReject)
+	}
+
+	steps := []struct {
+		name     string
+		sequence uint64
+		uid      string
+		hash     string
+		wantErr  error
+	}{
+		{"новое сообщение", 1, "ingest-a", "hash-a", nil},
+		{"повторная доставка", 1, "ingest-a", "hash-a", ErrDuplicateMessage},
+		{"номер повторился с другим содержимым", 1, "ingest-b", "hash-b", nil},
+		{"повторная доставка нового сообщения", 1, "ingest-b", "hash-b", ErrDuplicateMessage},
+		{"заказ отклонен политикой", 2, "ingest-a", "hash-a2", ErrOrderExists},
+		{"отклоненное сообщение не запоминается", 2, "ingest-a", "hash-a2", ErrOrderExists},
+	}
+	for name, repo := range repos {
+		ctx := context.Background()
+		for _, step := range steps {
+			msg := IngestedMessage{Subject: subject, Sequence: step.sequence, ContentHash: step.hash}
+			if err := repo.SaveIngested(ctx, testOrder(step.uid, "2021-11-26T06:22:19Z"), msg); !errors.Is(err, step.wantErr) {
+				t.Fatalf("%s: %s: ошибка %v, ожидается %v", name, step.name, err, step.wantErr)
+			}
+		}
+		if exists, err := repo.Exists(ctx, "ingest-b"); err != nil || !exists {
+			t.Errorf("%s: заказ из сообщения с повторившимся номером не сохранен: %v, %v", name, exists, err)
+		}
+	}
+}
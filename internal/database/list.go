@@ -0,0 +1,222 @@
+package database
+
+import (
+	"context"         // импорт пакета для отмены запроса вместе с http запросом
+	"database/sql"    // импорт стандартного пакета для работы с базой данных
+	"encoding/base64" // импорт пакета для кодирования курсора
+	"encoding/json"   // импорт пакета для работы с json
+	"errors"          // импорт пакета для работы с ошибками
+	"fmt"             // импорт пакета для форматированного вывода
+	"strconv"         // импорт пакета для преобразования строк в числа
+	"strings"         // импорт пакета для работы со строками
+	"time"            // импорт пакета для работы со временем
+
+	"go.opentelemetry.io/otel/attribute" // импорт атрибутов spans
+)
+
+// ListSort поле, по которому упорядочен список заказов
+type ListSort string
+
+const (
+	SortDateCreated ListSort = "date_created" // по дате создания заказа
+	SortAmount      ListSort = "amount"       // по сумме оплаты
+)
+
+// sortColumns выражение SQL для каждого поля сортировки
+var sortColumns = map[ListSort]string{
+	SortDateCreated: "o.date_created",
+	SortAmount:      "p.amount",
+}
+
+// Valid сообщает, поддерживается ли сортировка
+func (s ListSort) Valid() bool {
+	_, ok := sortColumns[s]
+	return ok
+}
+
+// Размер страницы списка заказов
+const (
+	DefaultListLimit = 50  // если размер не задан
+	MaxListLimit     = 500 // наибольший размер страницы
+)
+
+// ErrInvalidCursor возвращается, если курсор поврежден или получен для другой сортировки
+var ErrInvalidCursor = errors.New("некорректный курсор")
+
+// OrderFilter условия отбора заказов; пустые поля не ограничивают выборку
+type OrderFilter struct {
+	TrackNumber     string
+	CustomerID      string
+	DeliveryService string
+	Entry           string
+	Locale          string
+	CreatedFrom     time.Time // date_created не раньше, включительно
+	CreatedTo       time.Time // date_created раньше, не включительно
+	PaymentProvider string
+	PaymentBank     string
+	ItemBrand       string // в заказе есть товар этого бренда
+	ItemStatus      *int   // в заказе есть товар с этим статусом; вместе с ItemBrand условия относятся к одному товару
+}
+
+// ListOptions параметры запроса списка заказов
+type ListOptions struct {
+	Filter OrderFilter
+	Sort   ListSort // по умолчанию SortDateCreated
+	Desc   bool     // по убыванию
+	Limit  int      // размер страницы, по умолчанию DefaultListLimit, не больше MaxListLimit
+	Cursor string   // NextCursor предыдущей страницы; пустой для первой страницы
+}
+
+// OrderPage страница списка заказов
+type OrderPage struct {
+	Orders     []*Order `json:"orders"`
+	NextCursor string   `json:"next_cursor,omitempty"` // пустой на последней странице
+}
+
+// listCursor позиция в списке: значение поля сортировки и order_uid последнего заказа страницы.
+// Сортировка хранится в курсоре, чтобы его нельзя было применить к списку с другим порядком.
+type listCursor struct {
+	Sort  ListSort `json:"s"`
+	Desc  bool     `json:"d"`
+	Value string   `json:"v"`
+	UID   string   `json:"u"`
+}
+
+// encodeCursor кодирует курсор в непрозрачную строку для URL
+func encodeCursor(c listCursor) string {
+	data, _ := json.Marshal(c) // структура из строк и bool кодируется без ошибок
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор и возвращает значение поля сортировки в виде аргумента запроса
+func decodeCursor(s string, sort ListSort, desc bool) (value any, uid string, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort || c.Desc != desc {
+		return nil, "", ErrInvalidCursor
+	}
+	switch sort {
+	case SortDateCreated:
+		value, err = time.Parse(time.RFC3339Nano, c.Value)
+	case SortAmount:
+		value, err = strconv.Atoi(c.Value)
+	}
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	return value, c.UID, nil
+}
+
+// normalize подставляет сортировку и размер страницы по умолчанию и проверяет сортировку
+func (opts ListOptions) normalize() (ListOptions, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortDateCreated
+	}
+	if !opts.Sort.Valid() {
+		return opts, fmt.Errorf("Неизвестное поле сортировки: %q", opts.Sort)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultListLimit
+	}
+	opts.Limit = min(opts.Limit, MaxListLimit)
+	return opts, nil
+}
+
+// newOrderPage возвращает страницу из первых opts.Limit заказов и курсор, если заказов больше
+func newOrderPage(orders []*Order, opts ListOptions) *OrderPage {
+	page := &OrderPage{Orders: orders}
+	if len(orders) > opts.Limit {
+		page.Orders = orders[:opts.Limit]
+		last := page.Orders[opts.Limit-1]
+		page.NextCursor = encodeCursor(listCursor{Sort: opts.Sort, Desc: opts.Desc, Value: sortValue(last, opts.Sort), UID: last.OrderUID})
+	}
+	return page
+}
+
+// sortValue возвращает значение поля сортировки заказа для курсора
+func sortValue(order *Order, sort ListSort) string {
+	if sort == SortAmount {
+		return strconv.Itoa(order.Payment.Amount)
+	}
+	return order.DateCreated // драйвер возвращает timestamptz в формате RFC 3339
+}
+
+// Функция для получения страницы заказов по фильтру. Заказы упорядочены по полю сортировки и order_uid,
+// следующая страница начинается после последнего заказа предыдущей, поэтому новые заказы не сдвигают страницы.
+func ListOrders(ctx context.Context, db *sql.DB, opts ListOptions) (_ *OrderPage, err error) {
+	opts, err = opts.normalize()
+	if err != nil {
+		return nil, err
+	}
+	column := sortColumns[opts.Sort]
+
+	var where []string
+	var args []any
+	arg := func(value any) string { // добавляет аргумент запроса и возвращает его плейсхолдер
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	f := opts.Filter
+	for _, eq := range []struct{ column, value string }{
+		{"o.track_number", f.TrackNumber},
+		{"o.customer_id", f.CustomerID},
+		{"o.delivery_service", f.DeliveryService},
+		{"o.entry", f.Entry},
+		{"o.locale", f.Locale},
+		{"p.provider", f.PaymentProvider},
+		{"p.bank", f.PaymentBank},
+	} {
+		if eq.value != "" {
+			where = append(where, eq.column+" = "+arg(eq.value))
+		}
+	}
+	if !f.CreatedFrom.IsZero() {
+		where = append(where, "o.date_created >= "+arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		where = append(where, "o.date_created < "+arg(f.CreatedTo))
+	}
+	if f.ItemBrand != "" || f.ItemStatus != nil {
+		item := "EXISTS (SELECT 1 FROM items i WHERE i.order_uid = o.order_uid"
+		if f.ItemBrand != "" {
+			item += " AND i.brand = " + arg(f.ItemBrand)
+		}
+		if f.ItemStatus != nil {
+			item += " AND i.status = " + arg(*f.ItemStatus)
+		}
+		where = append(where, item+")")
+	}
+
+	direction, compare := "ASC", ">"
+	if opts.Desc {
+		direction, compare = "DESC", "<"
+	}
+	if opts.Cursor != "" {
+		value, uid, err := decodeCursor(opts.Cursor, opts.Sort, opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("(%s, o.order_uid) %s (%s, %s)", column, compare, arg(value), arg(uid)))
+	}
+
+	defer observeQuery(ctx, "list_orders", time.Now(), &err)
+	ctx, span := tracer.Start(ctx, "ListOrders")
+	span.SetAttributes(attribute.String("orders.sort", string(opts.Sort)), attribute.Int("orders.limit", opts.Limit))
+	defer func() { endSpan(span, err) }()
+
+	query := selectOrders
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, o.order_uid %s LIMIT %s", column, direction, direction, arg(opts.Limit+1)) // лишний заказ показывает, есть ли следующая страница
+
+	orders, err := queryOrders(ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newOrderPage(orders, opts), nil
+}
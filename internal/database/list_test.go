@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"                 // импорт пакета для вызова методов хранилища
+	"database/sql"            // импорт стандартного пакета для работы с базой данных
+	"errors"                  // импорт пакета для работы с ошибками
+	"os"                      // импорт пакета для чтения переменных окружения
+	"slices"                  // импорт пакета для сравнения срезов
+	"testing"                 // импорт пакета для тестов
+	"time"                    // импорт пакета для работы со временем
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+)
+
+// testDSNEnv переменная окружения со строкой подключения к тестовой базе данных.
+// Проверки PostgresRepository пропускаются, если она не задана.
+const testDSNEnv = "ORDERS_TEST_DSN"
+
+// openTestDB подключается к тестовой базе данных из ORDERS_TEST_DSN и применяет миграции; без нее тест пропускается
+func openTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s не задана, пропускаем проверку с базой данных", testDSNEnv)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// listCustomer customer_id заказов теста списка: все запросы фильтруют по нему,
+// поэтому другие заказы в тестовой базе данных не попадают в страницы
+const listCustomer = "list-test"
+
+// listOrders заказы с одинаковыми date_created и amount, порядок среди которых определяет order_uid.
+// Дата f отличается от a и c долями секунды, дата g совпадает с b и e в другом часовом поясе.
+func listOrders() []*Order {
+	orders := []struct {
+		uid, created string
+		amount       int
+	}{
+		{"a", "2021-01-01T00:00:00Z", 300},
+		{"b", "2021-01-02T00:00:00Z", 100},
+		{"c", "2021-01-01T00:00:00Z", 200},
+		{"d", "2021-01-03T00:00:00Z", 100},
+		{"e", "2021-01-02T00:00:00Z", 300},
+		{"f", "2021-01-01T00:00:00.5Z", 100},
+		{"g", "2021-01-02T03:00:00+03:00", 200},
+	}
+	result := make([]*Order, len(orders))
+	for i, o := range orders {
+		order := testOrder("list-"+o.uid, o.created)
+		order.CustomerID = listCustomer
+		order.Payment.Amount = o.amount
+		result[i] = order
+	}
+	result[0].Items[0].Brand, result[0].Items[0].Status = "Vivienne Sabo", 202
+	result[2].Items = []Item{{ChrtID: 1, Brand: "Vivienne Sabo", Status: 100}, {ChrtID: 2, Brand: "Other", Status: 202}}
+	result[3].Payment.Bank = "alpha"
+	return result
+}
+
+// listRepositories возвращает хранилища с заказами listOrders: в памяти и, если задана ORDERS_TEST_DSN, в PostgreSQL
+func listRepositories(t *testing.T) map[string]OrderRepository {
+	repos := map[string]OrderRepository{"memory": NewMemoryRepository(config.UpsertReplace)}
+	if os.Getenv(testDSNEnv) != "" {
+		db := openTestDB(t)
+		t.Cleanup(func() {
+			if _, err := db.Exec(`DELETE FROM orders WHERE customer_id = $1`, listCustomer); err != nil {
+				t.Error(err)
+			}
+		})
+		repos["postgres"] = NewPostgresRepository(db, config.UpsertReplace)
+	}
+	for _, repo := range repos {
+		for _, order := range listOrders() {
+			if err := repo.Save(context.Background(), order); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return repos
+}
+
+// listAll проходит список страницами по limit заказов и возвращает order_uid без префикса list-
+func listAll(t *testing.T, repo OrderRepository, opts ListOptions, limit int) []string {
+	opts.Filter.CustomerID = listCustomer
+	opts.Limit = limit
+	var uids []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("курсор не продвигается")
+		}
+		page, err := repo.List(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Orders) > limit {
+			t.Fatalf("на странице %d заказов, ожидается не больше %d", len(page.Orders), limit)
+		}
+		for _, order := range page.Orders {
+			uids = append(uids, order.OrderUID[len("list-"):])
+		}
+		if page.NextCursor == "" {
+			return uids
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	tests := []struct {
+		sort ListSort
+		desc bool
+		want []string
+	}{
+		{SortDateCreated, false, []string{"a", "c", "f", "b", "e", "g", "d"}},
+		{SortDateCreated, true, []string{"d", "g", "e", "b", "f", "c", "a"}},
+		{SortAmount, false, []string{"b", "d", "f", "c", "g", "a", "e"}},
+		{SortAmount, true, []string{"e", "a", "g", "c", "f", "d", "b"}},
+	}
+	for name, repo := range listRepositories(t) {
+		for _, tt := range tests {
+			for _, limit := range []int{1, 2, 3, 7, 10} {
+				got := listAll(t, repo, ListOptions{Sort: tt.sort, Desc: tt.desc}, limit)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("%s: sort=%s desc=%v limit=%d: %v, ожидается %v", name, tt.sort, tt.desc, limit, got, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	status := func(s int) *int { return &s }
+	tests := []struct {
+		name   string
+		filter OrderFilter
+		want   []string
+	}{
+		{"date_created с", OrderFilter{CreatedFrom: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)}, []string{"b", "e", "g", "d"}},
+		{"date_created до", OrderFilter{CreatedTo: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)}, []string{"a", "c", "f"}},
+		{"бренд", OrderFilter{ItemBrand: "Vivienne Sabo"}, []string{"a", "c"}},
+		{"бренд и статус одного товара", OrderFilter{ItemBrand: "Vivienne Sabo", ItemStatus: status(202)}, []string{"a"}},
+		{"статус", OrderFilter{ItemStatus: status(202)}, []string{"a", "c"}},
+		{"банк", OrderFilter{PaymentBank: "alpha"}, []string{"d"}},
+		{"нет совпадений", OrderFilter{TrackNumber: "unknown"}, nil},
+	}
+	for name, repo := range listRepositories(t) {
+		for _, tt := range tests {
+			got := listAll(t, repo, ListOptions{Filter: tt.filter, Sort: SortDateCreated}, 2)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s: %s: %v, ожидается %v", name, tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestListInvalidCursor(t *testing.T) {
+	for name, repo := range listRepositories(t) {
+		ctx := context.Background()
+		first, err := repo.List(ctx, ListOptions{Filter: OrderFilter{CustomerID: listCustomer}, Sort: SortAmount, Limit: 2})
+		if err != nil || first.NextCursor == "" {
+			t.Fatalf("%s: первая страница: %+v, %v", name, first, err)
+		}
+		tests := []struct {
+			name string
+			opts ListOptions
+		}{
+			{"поврежденный курсор", ListOptions{Sort: SortAmount, Cursor: "not a cursor"}},
+			{"курсор другой сортировки", ListOptions{Sort: SortDateCreated, Cursor: first.NextCursor}},
+			{"курсор другого направления", ListOptions{Sort: SortAmount, Desc: true, Cursor: first.NextCursor}},
+			{"курсор с некорректным значением", ListOptions{Sort: SortAmount,
+				Cursor: encodeCursor(listCursor{Sort: SortAmount, Value: "many", UID: "list-a"})}},
+		}
+		for _, tt := range tests {
+			if _, err := repo.List(ctx, tt.opts); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("%s: %s: ошибка %v, ожидается %v", name, tt.name, err, ErrInvalidCursor)
+			}
+		}
+		if _, err := repo.List(ctx, ListOptions{Sort: "price"}); err == nil {
+			t.Errorf("%s: нет ошибки для неизвестной сортировки", name)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"cmp"                     // импорт пакета для сравнения значений
+	"context"                 // импорт пакета для совместимости с интерфейсом хранилища
+	"fmt"                     // импорт пакета для форматированного вывода
+	"slices"                  // импорт пакета для сортировки и отбора заказов
+	"sort"                    // импорт пакета для сортировки
+	"strings"                 // импорт пакета для сравнения строк
+	"sync"                    // импорт пакета для синхронизации goroutine
+	"time"                    // импорт пакета для работы со временем
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+)
+
+// MemoryRepository хранилище заказов в памяти для тестов и локальных демонстраций.
+// Хранит копии заказов, поэтому изменение переданных или полученных структур не влияет на хранилище.
+type MemoryRepository struct {
+	mu       sync.RWMutex
+	orders   map[string]*Order
+	ingested map[ingestKey]string // хэш содержимого сохраненного сообщения по каналу и номеру
+	policy   config.UpsertPolicy
+}
+
+// ingestKey канал и номер сообщения nats-streaming
+type ingestKey struct {
+	subject  string
+	sequence uint64
+}
+
+var (
+	_ OrderRepository  = (*MemoryRepository)(nil)
+	_ IngestRepository = (*MemoryRepository)(nil)
+)
+
+// NewMemoryRepository создает пустое хранилище заказов в памяти
+func NewMemoryRepository(policy config.UpsertPolicy) *MemoryRepository {
+	return &MemoryRepository{orders: make(map[string]*Order), ingested: make(map[ingestKey]string), policy: policy}
+}
+
+func (r *MemoryRepository) Save(_ context.Context, order *Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save(order)
+}
+
+// SaveIngested сохраняет заказ из сообщения по тем же правилам, что и SaveIngestedOrder:
+// дубликатом считается сообщение с тем же каналом, номером и хэшем содержимого,
+// а отметка о сообщении не запоминается, если заказ не сохранен.
+func (r *MemoryRepository) SaveIngested(_ context.Context, order *Order, msg IngestedMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := ingestKey{subject: msg.Subject, sequence: msg.Sequence}
+	if hash, ok := r.ingested[key]; ok && hash == msg.ContentHash {
+		return ErrDuplicateMessage
+	}
+	if err := r.save(order); err != nil {
+		return err
+	}
+	r.ingested[key] = msg.ContentHash
+	return nil
+}
+
+// save сохраняет копию заказа согласно политике хранилища; вызывается под блокировкой
+func (r *MemoryRepository) save(order *Order) error {
+	if existing, ok := r.orders[order.OrderUID]; ok {
+		switch r.policy {
+		case config.UpsertReplace:
+		case config.UpsertReject:
+			return ErrOrderExists
+		case config.UpsertKeepNewest:
+			newer, err := createdAfter(order.DateCreated, existing.DateCreated)
+			if err != nil {
+				return err
+			}
+			if !newer {
+				return ErrOrderNotNewer
+			}
+		default:
+			return fmt.Errorf("Неизвестная политика сохранения заказа: %q", r.policy)
+		}
+	}
+	r.orders[order.OrderUID] = order.Clone()
+	return nil
+}
+
+func (r *MemoryRepository) Get(_ context.Context, orderUID string) (*Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	order, ok := r.orders[orderUID]
+	if !ok {
+		return nil, nil
+	}
+	return order.Clone(), nil
+}
+
+// List возвращает страницу заказов в том же порядке, что и ListOrders: по полю сортировки, затем по order_uid.
+// date_created сравнивается как время, а не как строка.
+func (r *MemoryRepository) List(_ context.Context, opts ListOptions) (*OrderPage, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return nil, err
+	}
+	direction := 1
+	if opts.Desc {
+		direction = -1
+	}
+	orders := slices.DeleteFunc(r.snapshot(), func(order *Order) bool { return !opts.Filter.match(order) })
+	slices.SortFunc(orders, func(a, b *Order) int {
+		return direction * compareOrder(a, opts.Sort, sortKey(b, opts.Sort), b.OrderUID)
+	})
+	if opts.Cursor != "" {
+		value, uid, err := decodeCursor(opts.Cursor, opts.Sort, opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+		start := slices.IndexFunc(orders, func(order *Order) bool { // первый заказ после курсора
+			return direction*compareOrder(order, opts.Sort, value, uid) > 0
+		})
+		if start < 0 {
+			start = len(orders)
+		}
+		orders = orders[start:]
+	}
+	return newOrderPage(orders[:min(len(orders), opts.Limit+1)], opts), nil
+}
+
+// snapshot возвращает копии всех заказов, упорядоченные по order_uid
+func (r *MemoryRepository) snapshot() []*Order {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orders := make([]*Order, 0, len(r.orders))
+	for _, order := range r.orders {
+		orders = append(orders, order.Clone())
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderUID < orders[j].OrderUID })
+	return orders
+}
+
+// sortKey возвращает значение поля сортировки заказа в том виде, в котором его возвращает decodeCursor
+func sortKey(order *Order, sort ListSort) any {
+	if sort == SortAmount {
+		return order.Payment.Amount
+	}
+	created, _ := time.Parse(time.RFC3339Nano, order.DateCreated) // Save не проверяет формат, некорректная дата идет первой
+	return created
+}
+
+// compareOrder сравнивает заказ с позицией списка: значением поля сортировки, затем order_uid
+func compareOrder(order *Order, sort ListSort, value any, uid string) int {
+	var c int
+	switch v := value.(type) {
+	case time.Time:
+		c = sortKey(order, sort).(time.Time).Compare(v)
+	case int:
+		c = cmp.Compare(order.Payment.Amount, v)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(order.OrderUID, uid)
+}
+
+// match сообщает, подходит ли заказ под фильтр; условия те же, что в ListOrders
+func (f OrderFilter) match(order *Order) bool {
+	for _, eq := range []struct{ filter, value string }{
+		{f.TrackNumber, order.TrackNumber},
+		{f.CustomerID, order.CustomerID},
+		{f.DeliveryService, order.DeliveryService},
+		{f.Entry, order.Entry},
+		{f.Locale, order.Locale},
+		{f.PaymentProvider, order.Payment.Provider},
+		{f.PaymentBank, order.Payment.Bank},
+	} {
+		if eq.filter != "" && eq.filter != eq.value {
+			return false
+		}
+	}
+	if !f.CreatedFrom.IsZero() || !f.CreatedTo.IsZero() {
+		created, err := time.Parse(time.RFC3339Nano, order.DateCreated)
+		if err != nil || created.Before(f.CreatedFrom) || (!f.CreatedTo.IsZero() && !created.Before(f.CreatedTo)) {
+			return false
+		}
+	}
+	if f.ItemBrand != "" || f.ItemStatus != nil {
+		return slices.ContainsFunc(order.Items, func(item Item) bool { // оба условия относятся к одному товару
+			return (f.ItemBrand == "" || item.Brand == f.ItemBrand) && (f.ItemStatus == nil || item.Status == *f.ItemStatus)
+		})
+	}
+	return true
+}
+
+func (r *MemoryRepository) Delete(_ context.Context, orderUID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.orders, orderUID)
+	return nil
+}
+
+func (r *MemoryRepository) Exists(_ context.Context, orderUID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.orders[orderUID]
+	return ok, nil
+}
+
+func (r *MemoryRepository) Stream(fn func(order *Order) error) error {
+	for _, order := range r.snapshot() { // берем снимок, чтобы fn мог обращаться к хранилищу
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createdAfter сообщает, создан ли заказ a позже заказа b по date_created в формате RFC3339
+func createdAfter(a, b string) (bool, error) {
+	ta, err := time.Parse(time.RFC3339, a)
+	if err != nil {
+		return false, fmt.Errorf("Некорректный date_created %q: %v", a, err)
+	}
+	tb, err := time.Parse(time.RFC3339, b)
+	if err != nil {
+		return false, fmt.Errorf("Некорректный date_created %q: %v", b, err)
+	}
+	return ta.After(tb), nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"                 // импорт пакета для вызова методов хранилища
+	"errors"                  // импорт пакета для работы с ошибками
+	"strings"                 // импорт пакета для работы со строками
+	"testing"                 // импорт пакета для тестов
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+)
+
+// testOrder возвращает заказ с заданными UID и date_created
+func testOrder(uid, dateCreated string) *Order {
+	return &Order{OrderUID: uid, DateCreated: dateCreated, Payment: Payment{Amount: 100}, Items: []Item{{ChrtID: 1, Name: "item"}}}
+}
+
+// orderUpdateColumns колонки orders, которые перезаписываются при замене заказа
+var orderUpdateColumns = []string{"track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "updated_at"}
+
+func TestOrderConflictClauses(t *testing.T) {
+	tests := []struct {
+		policy    config.UpsertPolicy
+		updates   bool   // существующий заказ перезаписывается
+		condition string // условие перезаписи
+	}{
+		{config.UpsertReplace, true, ""},
+		{config.UpsertReject, false, ""},
+		{config.UpsertKeepNewest, true, "WHERE orders.date_created < EXCLUDED.date_created"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			clause, ok := orderConflictClauses[tt.policy]
+			if !ok {
+				t.Fatalf("нет ON CONFLICT для политики %q", tt.policy)
+			}
+			if !strings.HasPrefix(clause, "ON CONFLICT (order_uid)") {
+				t.Errorf("конфликт определяется не по order_uid: %s", clause)
+			}
+			if !tt.updates {
+				if !strings.HasSuffix(clause, "DO NOTHING") {
+					t.Errorf("ожидается DO NOTHING: %s", clause)
+				}
+				return
+			}
+			for _, column := range orderUpdateColumns {
+				if !strings.Contains(clause, column+" = ") {
+					t.Errorf("колонка %s не перезаписывается: %s", column, clause)
+				}
+			}
+			if hasWhere := strings.Contains(clause, "WHERE"); hasWhere != (tt.condition != "") {
+				t.Errorf("условие перезаписи = %v, ожидается %q: %s", hasWhere, tt.condition, clause)
+			}
+			if tt.condition != "" && !strings.HasSuffix(clause, tt.condition) {
+				t.Errorf("ожидается условие %q: %s", tt.condition, clause)
+			}
+		})
+	}
+	for policy := range orderConflictClauses {
+		if !policy.Valid() {
+			t.Errorf("ON CONFLICT для неизвестной политики %q", policy)
+		}
+	}
+}
+
+func TestMemoryRepositorySavePolicy(t *testing.T) {
+	const older, newer = "2021-11-26T06:22:19Z", "2021-11-27T06:22:19Z"
+	tests := []struct {
+		policy  config.UpsertPolicy
+		first   string // date_created сохраненного заказа
+		second  string // date_created повторного заказа
+		wantErr error
+		want    string // date_created заказа в хранилище после повторного сохранения
+	}{
+		{config.UpsertReplace, newer, older, nil, older},
+		{config.UpsertReject, older, newer, ErrOrderExists, older},
+		{config.UpsertKeepNewest, older, newer, nil, newer},
+		{config.UpsertKeepNewest, newer, older, ErrOrderNotNewer, newer},
+		{config.UpsertKeepNewest, older, older, ErrOrderNotNewer, older},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+" "+tt.first+" "+tt.second, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewMemoryRepository(tt.policy)
+			if err := repo.Save(ctx, testOrder("a", tt.first)); err != nil {
+				t.Fatalf("первое сохранение: %v", err)
+			}
+			second := testOrder("a", tt.second)
+			second.Items = append(second.Items, Item{ChrtID: 2})
+			if err := repo.Save(ctx, second); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("повторное сохранение: ошибка %v, ожидается %v", err, tt.wantErr)
+			}
+			got, err := repo.Get(ctx, "a")
+			if err != nil || got == nil {
+				t.Fatalf("Get() = %v, %v", got, err)
+			}
+			if got.DateCreated != tt.want {
+				t.Errorf("date_created = %s, ожидается %s", got.DateCreated, tt.want)
+			}
+			if replaced := tt.wantErr == nil; replaced != (len(got.Items) == 2) {
+				t.Errorf("товаров %d: набор товаров должен заменяться вместе с заказом", len(got.Items))
+			}
+		})
+	}
+}
+
+func TestMemoryRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(config.UpsertReplace)
+	order := testOrder("a", "2021-11-26T06:22:19Z")
+	if err := repo.Save(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	order.Items[0].Name = "changed" // изменение переданного заказа
+	got, _ := repo.Get(ctx, "a")
+	got.Items[0].Name = "changed too" // изменение полученного заказа
+	again, _ := repo.Get(ctx, "a")
+	if again.Items[0].Name != "item" {
+		t.Errorf("заказ в хранилище изменился: %q", again.Items[0].Name)
+	}
+}
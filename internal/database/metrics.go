@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"  // импорт пакета с контекстом операции
+	"errors"   // импорт пакета для работы с ошибками
+	"log/slog" // импорт пакета структурированного логирования
+	"time"     // импорт пакета для работы со временем
+
+	"github.com/prometheus/client_golang/prometheus"          // импорт библиотеки метрик prometheus
+	"github.com/prometheus/client_golang/prometheus/promauto" // импорт пакета для регистрации метрик при объявлении
+)
+
+// queryDuration длительность операций с базой данных по операции и результату
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "orders_db_operation_duration_seconds",
+	Help:    "Длительность операций с базой данных.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation", "result"})
+
+// observeQuery записывает длительность операции, начатой в start, в метрику и отладочный лог;
+// вызывается через defer с указателем на возвращаемую ошибку
+func observeQuery(ctx context.Context, operation string, start time.Time, err *error) {
+	result := "ok"
+	switch {
+	case errors.Is(*err, ErrOrderExists), errors.Is(*err, ErrOrderNotNewer), errors.Is(*err, ErrDuplicateMessage):
+		result = "rejected" // политика сохранения или дедупликация оставили данные без изменений
+	case *err != nil:
+		result = "error"
+	}
+	elapsed := time.Since(start)
+	queryDuration.WithLabelValues(operation, result).Observe(elapsed.Seconds())
+	slog.DebugContext(ctx, "Операция с БД", "operation", operation, "result", result, "duration", elapsed)
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"      // импорт пакета для работы с контекстом
+	"database/sql" // импорт стандартного пакета для работы с базой данных
+	"embed"        // импорт пакета для встраивания файлов в бинарник
+	"fmt"          // импорт пакета для форматированного вывода
+	"io/fs"        // импорт пакета для чтения встроенных файлов
+	"sort"         // импорт пакета для сортировки
+	"strconv"      // импорт пакета для преобразования строк в числа
+	"strings"      // импорт пакета для работы со строками
+	"time"         // импорт пакета для работы со временем
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS // SQL миграции, встроенные в бинарник
+
+// migrationLockID ключ advisory lock, который не дает двум экземплярам применять миграции одновременно
+const migrationLockID = 7_245_001
+
+// Структура для хранения одной миграции схемы
+type Migration struct {
+	Version int64  // номер версии из имени файла, например 1 для 0001_init.up.sql
+	Name    string // имя миграции из имени файла, например init
+	Up      string // SQL для применения миграции
+	Down    string // SQL для отката миграции
+}
+
+// Структура для хранения состояния миграции в базе данных
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time // время применения, nil если миграция не применена
+}
+
+// Функция для получения списка миграций, отсортированных по версии
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		base := strings.TrimPrefix(file, "migrations/")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Некорректное имя файла миграции: %s", base)
+		}
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Некорректная версия миграции %s: %v", base, err)
+		}
+
+		var name, direction string
+		switch {
+		case strings.HasSuffix(parts[1], ".up.sql"):
+			name, direction = strings.TrimSuffix(parts[1], ".up.sql"), "up"
+		case strings.HasSuffix(parts[1], ".down.sql"):
+			name, direction = strings.TrimSuffix(parts[1], ".down.sql"), "down"
+		default:
+			return nil, fmt.Errorf("Файл миграции должен оканчиваться на .up.sql или .down.sql: %s", base)
+		}
+
+		data, err := migrationsFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("Миграции с версией %d имеют разные имена: %s и %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("Для миграции %04d_%s нужны файлы up и down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Функция для применения всех непримененных миграций. Возвращает список примененных версий.
+func MigrateUp(db *sql.DB) ([]int64, error) {
+	var applied []int64
+	err := withMigrationLock(db, func(conn *sql.Conn, states []MigrationState) error {
+		for _, state := range states {
+			if state.AppliedAt != nil {
+				continue
+			}
+			err := runMigration(conn, state.Version, state.Up, func(tx *sql.Tx) error {
+				_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, state.Version, state.Name)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			applied = append(applied, state.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Функция для отката последних steps примененных миграций. Возвращает список откаченных версий.
+func MigrateDown(db *sql.DB, steps int) ([]int64, error) {
+	var reverted []int64
+	err := withMigrationLock(db, func(conn *sql.Conn, states []MigrationState) error {
+		for i := len(states) - 1; i >= 0 && len(reverted) < steps; i-- {
+			state := states[i]
+			if state.AppliedAt == nil {
+				continue
+			}
+			err := runMigration(conn, state.Version, state.Down, func(tx *sql.Tx) error {
+				_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, state.Version)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			reverted = append(reverted, state.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Функция для получения состояния всех миграций
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	var result []MigrationState
+	err := withMigrationLock(db, func(conn *sql.Conn, states []MigrationState) error {
+		result = states
+		return nil
+	})
+	return result, err
+}
+
+// Функция для принудительной установки версии схемы без выполнения SQL.
+// Миграции с версией не выше version отмечаются примененными, остальные — непримененными.
+// Используется, чтобы восстановить учет после ручного исправления схемы.
+func ForceMigrationVersion(db *sql.DB, version int64) error {
+	return withMigrationLock(db, func(conn *sql.Conn, states []MigrationState) error {
+		known := version == 0
+		for _, state := range states {
+			known = known || state.Version == version
+		}
+		if !known {
+			return fmt.Errorf("Миграция с версией %d не найдена", version)
+		}
+
+		tx, err := conn.BeginTx(context.Background(), nil)
+		if err != nil {
+			return fmt.Errorf("Ошибка при запуске транзакции: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.Exec(`DELETE FROM schema_migrations`); err != nil {
+			return fmt.Errorf("Ошибка очистки schema_migrations: %v", err)
+		}
+		for _, state := range states {
+			if state.Version > version {
+				break
+			}
+			if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, state.Version, state.Name); err != nil {
+				return fmt.Errorf("Ошибка записи версии %d: %v", state.Version, err)
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// withMigrationLock создает таблицу schema_migrations, берет advisory lock и передает в fn состояние миграций
+func withMigrationLock(db *sql.DB, fn func(conn *sql.Conn, states []MigrationState) error) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(context.Background()) // advisory lock принадлежит сессии, поэтому работаем в одном соединении
+	if err != nil {
+		return fmt.Errorf("Ошибка получения соединения: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("Ошибка блокировки миграций: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(context.Background(), `CREATE TABLE IF NOT EXISTS schema_migrations (
+	                                                  version    BIGINT PRIMARY KEY,
+	                                                  name       TEXT        NOT NULL,
+	                                                  applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`)
+	if err != nil {
+		return fmt.Errorf("Ошибка создания schema_migrations: %v", err)
+	}
+
+	rows, err := conn.QueryContext(context.Background(), `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("Ошибка получения schema_migrations: %v", err)
+	}
+	appliedAt := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			rows.Close()
+			return fmt.Errorf("Ошибка сканирования schema_migrations: %v", err)
+		}
+		appliedAt[version] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Ошибка итерации по строкам schema_migrations: %v", err)
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i].Migration = m
+		if at, ok := appliedAt[m.Version]; ok {
+			states[i].AppliedAt = &at
+		}
+	}
+
+	return fn(conn, states)
+}
+
+// runMigration выполняет SQL миграции и обновление schema_migrations в одной транзакции
+func runMigration(conn *sql.Conn, version int64, query string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка при запуске транзакции: %v", err)
+	}
+	if _, err := tx.Exec(query); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Ошибка выполнения миграции %d: %v", version, err)
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Ошибка записи версии %d: %v", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка при совершении транзакции миграции %d: %v", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+CREATE TABLE dead_letters (
+    id          BIGSERIAL PRIMARY KEY,
+    subject     TEXT        NOT NULL,
+    sequence    BIGINT      NOT NULL,
+    stage       TEXT        NOT NULL,
+    error       TEXT        NOT NULL,
+    payload     BYTEA       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    replayed_at TIMESTAMPTZ
+);
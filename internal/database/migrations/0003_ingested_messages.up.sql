@@ -0,0 +1,10 @@
+CREATE TABLE ingested_messages (
+    subject      TEXT        NOT NULL,
+    sequence     BIGINT      NOT NULL,
+    content_hash TEXT        NOT NULL,
+    order_uid    TEXT        NOT NULL,
+    ingested_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subject, sequence)
+);
+
+CREATE UNIQUE INDEX ingested_messages_content_hash_idx ON ingested_messages (content_hash);
@@ -0,0 +1,3 @@
+ALTER TABLE orders ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX orders_updated_at_idx ON orders (updated_at);
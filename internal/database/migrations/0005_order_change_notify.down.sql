@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS items_notify_changed ON items;
+DROP TRIGGER IF EXISTS payment_notify_changed ON payment;
+DROP TRIGGER IF EXISTS delivery_notify_changed ON delivery;
+DROP TRIGGER IF EXISTS orders_notify_changed ON orders;
+DROP FUNCTION IF EXISTS notify_order_changed();
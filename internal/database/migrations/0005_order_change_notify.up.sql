@@ -0,0 +1,24 @@
+-- Уведомляет канал order_changed об изменении заказа; payload - order_uid.
+-- Одинаковые уведомления в одной транзакции PostgreSQL объединяет, поэтому сохранение заказа дает одно уведомление.
+CREATE FUNCTION notify_order_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('order_changed', OLD.order_uid);
+    ELSE
+        PERFORM pg_notify('order_changed', NEW.order_uid);
+        IF TG_OP = 'UPDATE' AND NEW.order_uid IS DISTINCT FROM OLD.order_uid THEN
+            PERFORM pg_notify('order_changed', OLD.order_uid);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_notify_changed AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+CREATE TRIGGER delivery_notify_changed AFTER INSERT OR UPDATE OR DELETE ON delivery
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+CREATE TRIGGER payment_notify_changed AFTER INSERT OR UPDATE OR DELETE ON payment
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+CREATE TRIGGER items_notify_changed AFTER INSERT OR UPDATE OR DELETE ON items
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS items_brand_status_idx;
+DROP INDEX IF EXISTS payment_provider_bank_idx;
+DROP INDEX IF EXISTS payment_amount_idx;
+DROP INDEX IF EXISTS orders_delivery_service_idx;
+DROP INDEX IF EXISTS orders_track_number_idx;
+DROP INDEX IF EXISTS orders_customer_id_idx;
+DROP INDEX IF EXISTS orders_date_created_idx;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS customer_id;
@@ -0,0 +1,11 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id TEXT NOT NULL DEFAULT '';
+
+-- Индексы для списка заказов: сортировка с курсором по (date_created, order_uid) и (amount, order_uid),
+-- фильтры по полям с высокой избирательностью
+CREATE INDEX orders_date_created_idx ON orders (date_created, order_uid);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id, date_created);
+CREATE INDEX orders_track_number_idx ON orders (track_number);
+CREATE INDEX orders_delivery_service_idx ON orders (delivery_service, date_created);
+CREATE INDEX payment_amount_idx ON payment (amount, order_uid);
+CREATE INDEX payment_provider_bank_idx ON payment (provider, bank);
+CREATE INDEX items_brand_status_idx ON items (brand, status, order_uid);
@@ -0,0 +1,3 @@
+-- Откат не удастся, если после миграции были сохранены сообщения с одинаковым содержимым
+DROP INDEX IF EXISTS ingested_messages_content_hash_idx;
+CREATE UNIQUE INDEX ingested_messages_content_hash_idx ON ingested_messages (content_hash);
@@ -0,0 +1,5 @@
+-- Повторная доставка определяется только по (subject, sequence). Уникальный хэш содержимого отбрасывал
+-- и повторную публикацию того же заказа, например A(v1) -> A(v2) -> A(v1), поэтому индекс по хэшу
+-- оставлен только для поиска сообщений с одинаковым содержимым.
+DROP INDEX IF EXISTS ingested_messages_content_hash_idx;
+CREATE INDEX ingested_messages_content_hash_idx ON ingested_messages (content_hash);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS items_touch_order ON items;
+DROP TRIGGER IF EXISTS payment_touch_order ON payment;
+DROP TRIGGER IF EXISTS delivery_touch_order ON delivery;
+DROP FUNCTION IF EXISTS touch_order_updated_at();
@@ -0,0 +1,24 @@
+-- Изменение доставки, оплаты или товаров обновляет orders.updated_at, чтобы догрузка изменений
+-- после снимка кэша и после разрыва соединения для уведомлений видела и такие изменения.
+-- now() постоянно в пределах транзакции, поэтому SaveOrder, который уже обновил updated_at,
+-- и вставка нескольких товаров одной транзакцией не перезаписывают строку заказа повторно.
+CREATE FUNCTION touch_order_updated_at() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        UPDATE orders SET updated_at = now() WHERE order_uid = OLD.order_uid AND updated_at < now();
+    ELSE
+        UPDATE orders SET updated_at = now() WHERE order_uid = NEW.order_uid AND updated_at < now();
+        IF TG_OP = 'UPDATE' AND NEW.order_uid IS DISTINCT FROM OLD.order_uid THEN
+            UPDATE orders SET updated_at = now() WHERE order_uid = OLD.order_uid AND updated_at < now();
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER delivery_touch_order AFTER INSERT OR UPDATE OR DELETE ON delivery
+    FOR EACH ROW EXECUTE FUNCTION touch_order_updated_at();
+CREATE TRIGGER payment_touch_order AFTER INSERT OR UPDATE OR DELETE ON payment
+    FOR EACH ROW EXECUTE FUNCTION touch_order_updated_at();
+CREATE TRIGGER items_touch_order AFTER INSERT OR UPDATE OR DELETE ON items
+    FOR EACH ROW EXECUTE FUNCTION touch_order_updated_at();
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"                     // импорт пакета для форматированного вывода
+	"log/slog"                // импорт пакета структурированного логирования
+	"time"                    // импорт пакета для работы со временем
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+
+	"github.com/lib/pq" // импорт драйвера PostgreSQL
+)
+
+// OrderChangedChannel канал уведомлений, в который триггеры публикуют order_uid измененного заказа
+const OrderChangedChannel = "order_changed"
+
+// listenerPingInterval период проверки соединения, если уведомлений нет
+const listenerPingInterval = 90 * time.Second
+
+// OrderListener получает уведомления об изменении заказов через LISTEN/NOTIFY
+type OrderListener struct {
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// Функция для подписки на уведомления об изменении заказов.
+// onChange вызывается с order_uid измененного заказа. onReconnect вызывается после восстановления
+// соединения: уведомления, отправленные во время разрыва, потеряны, и вызывающий код должен догрузить изменения.
+// Обработчики вызываются последовательно из одной goroutine.
+func ListenOrderChanges(cfg config.DatabaseConfig, onChange func(orderUID string), onReconnect func()) (*OrderListener, error) {
+	listener := pq.NewListener(cfg.DSN(), time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("Ошибка соединения для уведомлений об изменении заказов", "error", err)
+		}
+	})
+	if err := listener.Listen(OrderChangedChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("Ошибка подписки на канал %s: %v", OrderChangedChannel, err)
+	}
+
+	l := &OrderListener{listener: listener, done: make(chan struct{})}
+	go l.run(onChange, onReconnect)
+	return l, nil
+}
+
+// run обрабатывает уведомления, пока не вызван Close
+func (l *OrderListener) run(onChange func(orderUID string), onReconnect func()) {
+	defer close(l.done)
+	for {
+		select {
+		case n, ok := <-l.listener.Notify:
+			if !ok {
+				return // канал закрывается при Close
+			}
+			if n == nil { // pq отправляет nil после восстановления соединения
+				onReconnect()
+				continue
+			}
+			onChange(n.Extra)
+		case <-time.After(listenerPingInterval):
+			if err := l.listener.Ping(); err != nil {
+				slog.Warn("Ошибка проверки соединения для уведомлений", "error", err)
+			}
+		}
+	}
+}
+
+// Close отписывается от уведомлений и закрывает соединение
+func (l *OrderListener) Close() error {
+	err := l.listener.Close()
+	<-l.done
+	return err
+}
@@ -1,9 +1,15 @@
 package database
 
 import (
-	"database/sql"        // импорт стандартного пакета для работы с базой данных
-	"fmt"                 // импорт пакета для форматированного вывода
-	_ "github.com/lib/pq" // импорт драйвера PostgreSQL
+	"context"                 // импорт пакета для отмены запросов вместе с http запросом
+	"database/sql"            // импорт стандартного пакета для работы с базой данных
+	"errors"                  // импорт пакета для работы с ошибками
+	"fmt"                     // импорт пакета для форматированного вывода
+	"log/slog"                // импорт пакета структурированного логирования
+	"time"                    // импорт пакета для работы со временем
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+
+	"github.com/lib/pq" // импорт драйвера PostgreSQL
 )
 
 // Структура для хранения информации о заказе
@@ -16,6 +22,7 @@ type Order struct {
 	Items             []Item   `json:"items"`
 	Locale            string   `json:"locale"`
 	InternalSignature string   `json:"internal_signature"`
+	CustomerID        string   `json:"customer_id"`
 	DeliveryService   string   `json:"delivery_service"`
 	ShardKey          string   `json:"shardkey"`
 	SmID              int      `json:"sm_id"`
@@ -23,6 +30,16 @@ type Order struct {
 	OofShard          string   `json:"oof_shard"`
 }
 
+// Clone возвращает копию заказа с собственным срезом товаров.
+// Все остальные поля заказа значимые, поэтому изменение копии не затрагивает оригинал.
+func (o *Order) Clone() *Order {
+	c := *o
+	if o.Items != nil {
+		c.Items = append(make([]Item, 0, len(o.Items)), o.Items...)
+	}
+	return &c
+}
+
 // Структура для хранения информации о доставке
 type Delivery struct {
 	Name    string `json:"name"`
@@ -64,9 +81,8 @@ type Item struct {
 }
 
 // Функция для подключения к базе данных PostgreSQL
-func ConnectDB() (*sql.DB, error) {
-	connStr := "user=postgres password=12345 dbname=l0db sslmode=disable" // строка подключения к базе данных
-	db, err := sql.Open("postgres", connStr)                              // открываем соединение с базой данных
+func ConnectDB(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN()) // открываем соединение с базой данных
 	if err != nil {
 		return nil, fmt.Errorf("Ошибка подключения к базе данных: %v", err) // возвращаем ошибку в случае неудачного подключения
 	}
@@ -75,174 +91,281 @@ func ConnectDB() (*sql.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Ошибка проверки соединения с базой данных: %v", err) // возвращаем ошибку в случае неудачной проверки
 	}
-	fmt.Println("Подключено к базе данных") // выводим сообщение об успешном подключении
+	slog.Info("Подключено к базе данных", "host", cfg.Host, "name", cfg.Name) // пишем в лог сообщение об успешном подключении
 
 	return db, nil // возвращаем объект базы данных и nil в случае успешного подключения
 }
 
-// Функция для сохранения заказа в базу данных
-func SaveOrder(db *sql.DB, order *Order) error {
-	tx, err := db.Begin() // начинаем транзакцию
+// Ошибки, которые возвращает SaveOrder, когда политика запрещает перезаписать существующий заказ
+var (
+	ErrOrderExists   = errors.New("заказ уже существует")                               // политика reject
+	ErrOrderNotNewer = errors.New("сохраненный заказ не старше нового по date_created") // политика keep-newest
+)
+
+// orderConflictClauses задает ON CONFLICT для таблицы orders в зависимости от политики
+var orderConflictClauses = map[config.UpsertPolicy]string{
+	config.UpsertReplace: `ON CONFLICT (order_uid) DO UPDATE SET
+	                       track_number = EXCLUDED.track_number, entry = EXCLUDED.entry, locale = EXCLUDED.locale,
+	                       internal_signature = EXCLUDED.internal_signature, customer_id = EXCLUDED.customer_id, delivery_service = EXCLUDED.delivery_service,
+	                       shardkey = EXCLUDED.shardkey, sm_id = EXCLUDED.sm_id, date_created = EXCLUDED.date_created,
+	                       oof_shard = EXCLUDED.oof_shard, updated_at = now()`,
+	config.UpsertReject: `ON CONFLICT (order_uid) DO NOTHING`,
+	config.UpsertKeepNewest: `ON CONFLICT (order_uid) DO UPDATE SET
+	                          track_number = EXCLUDED.track_number, entry = EXCLUDED.entry, locale = EXCLUDED.locale,
+	                          internal_signature = EXCLUDED.internal_signature, customer_id = EXCLUDED.customer_id, delivery_service = EXCLUDED.delivery_service,
+	                          shardkey = EXCLUDED.shardkey, sm_id = EXCLUDED.sm_id, date_created = EXCLUDED.date_created,
+	                          oof_shard = EXCLUDED.oof_shard, updated_at = now()
+	                          WHERE orders.date_created < EXCLUDED.date_created`,
+}
+
+// Функция для сохранения заказа в базу данных.
+// Существующий заказ обрабатывается согласно policy: все поля orders, delivery и payment
+// перезаписываются, а набор товаров заменяется целиком в той же транзакции.
+func SaveOrder(ctx context.Context, db *sql.DB, order *Order, policy config.UpsertPolicy) (err error) {
+	defer observeQuery(ctx, "save_order", time.Now(), &err)
+	ctx, span := startOperation(ctx, "SaveOrder", order.OrderUID)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := db.BeginTx(ctx, nil) // начинаем транзакцию
 	if err != nil {
 		return fmt.Errorf("Ошибка при запуске транзакции: %v", err) // возвращаем ошибку в случае неудачного начала транзакции
 	}
 
-	_, err = tx.Exec(`INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, delivery_service, shardkey, sm_id, date_created, oof_shard)
-	                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	                   ON CONFLICT (order_uid) DO UPDATE SET track_number = EXCLUDED.track_number`,
-		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard)
+	err = saveOrderTx(ctx, tx, order, policy) // сохраняем заказ в рамках транзакции
+	if err != nil {
+		tx.Rollback() // откатываем транзакцию в случае ошибки
+		return err
+	}
+
+	err = commitTx(ctx, tx) // подтверждаем транзакцию
+	if err != nil {
+		return fmt.Errorf("Ошибка при совершении транзакции: %v", err) // возвращаем ошибку в случае неудачного подтверждения транзакции
+	}
+
+	return nil // возвращаем nil в случае успешного сохранения заказа
+}
+
+// saveOrderTx записывает заказ в таблицы orders, delivery, payment и items в переданной транзакции
+func saveOrderTx(ctx context.Context, tx *sql.Tx, order *Order, policy config.UpsertPolicy) error {
+	conflict, ok := orderConflictClauses[policy]
+	if !ok {
+		return fmt.Errorf("Неизвестная политика сохранения заказа: %q", policy)
+	}
+
+	res, err := execTx(ctx, tx, "INSERT orders", `INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
+	                     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) `+conflict,
+		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard)
 	if err != nil {
-		tx.Rollback()                                        // откатываем транзакцию в случае ошибки
 		return fmt.Errorf("Ошибка при вводе order: %v", err) // возвращаем ошибку в случае неудачного ввода данных о заказе
 	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Ошибка при вводе order: %v", err)
+	}
+	if affected == 0 { // строка не вставлена и не обновлена, значит политика запретила перезапись
+		if policy == config.UpsertReject {
+			return ErrOrderExists
+		}
+		return ErrOrderNotNewer
+	}
 
-	_, err = tx.Exec(`INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email)
+	_, err = execTx(ctx, tx, "UPSERT delivery", `INSERT INTO delivery (order_uid, name, phone, zip, city, address, region, email)
 	                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	                   ON CONFLICT (order_uid) DO UPDATE SET name = EXCLUDED.name`,
+	                   ON CONFLICT (order_uid) DO UPDATE SET
+	                   name = EXCLUDED.name, phone = EXCLUDED.phone, zip = EXCLUDED.zip, city = EXCLUDED.city,
+	                   address = EXCLUDED.address, region = EXCLUDED.region, email = EXCLUDED.email`,
 		order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email)
 	if err != nil {
-		tx.Rollback()                                           // откатываем транзакцию в случае ошибки
 		return fmt.Errorf("Ошибка при вводе delivery: %v", err) // возвращаем ошибку в случае неудачного ввода данных о доставке
 	}
 
-	_, err = tx.Exec(`INSERT INTO payment (order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+	_, err = execTx(ctx, tx, "UPSERT payment", `INSERT INTO payment (order_uid, transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
 	                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	                   ON CONFLICT (order_uid) DO UPDATE SET transaction = EXCLUDED.transaction`,
+	                   ON CONFLICT (order_uid) DO UPDATE SET
+	                   transaction = EXCLUDED.transaction, request_id = EXCLUDED.request_id, currency = EXCLUDED.currency,
+	                   provider = EXCLUDED.provider, amount = EXCLUDED.amount, payment_dt = EXCLUDED.payment_dt, bank = EXCLUDED.bank,
+	                   delivery_cost = EXCLUDED.delivery_cost, goods_total = EXCLUDED.goods_total, custom_fee = EXCLUDED.custom_fee`,
 		order.OrderUID, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee)
 	if err != nil {
-		tx.Rollback()                                          // откатываем транзакцию в случае ошибки
 		return fmt.Errorf("Ошибка при вводе payment: %v", err) // возвращаем ошибку в случае неудачного ввода данных об оплате
 	}
 
+	_, err = execTx(ctx, tx, "DELETE items", `DELETE FROM items WHERE order_uid = $1`, order.OrderUID) // удаляем прежний набор товаров заказа
+	if err != nil {
+		return fmt.Errorf("Ошибка при удалении items: %v", err) // возвращаем ошибку в случае неудачного удаления товаров
+	}
+
 	for _, item := range order.Items { // цикл для ввода данных о каждом товаре в заказе
-		_, err = tx.Exec(`INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
-		                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		                   ON CONFLICT (id) DO UPDATE SET chrt_id = EXCLUDED.chrt_id`,
+		_, err = execTx(ctx, tx, "INSERT items", `INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
+		                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
 			order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
 		if err != nil {
-			tx.Rollback()                                       // откатываем транзакцию в случае ошибки
 			return fmt.Errorf("Ошибка при вводе item: %v", err) // возвращаем ошибку в случае неудачного ввода данных о товаре
 		}
 	}
 
-	err = tx.Commit() // подтверждаем транзакцию
-	if err != nil {
-		return fmt.Errorf("Ошибка при совершении транзакции: %v", err) // возвращаем ошибку в случае неудачного подтверждения транзакции
-	}
-
-	return nil // возвращаем nil в случае успешного сохранения заказа
+	return nil
 }
 
-// Функция для получения заказа из базы данных по его ID
-func GetOrderFromDB(db *sql.DB, orderUID string) (*Order, error) {
-	var order Order
-	order.Items = make([]Item, 0) // инициализируем пустой срез для товаров в заказе
+// OrderBatchSize количество заказов, которые загружаются из базы данных за один запрос
+const OrderBatchSize = 1000
 
-	// Получаем данные о заказе из таблицы orders
-	err := db.QueryRow(`SELECT order_uid, track_number, entry, locale, internal_signature, delivery_service, shardkey, sm_id, date_created, oof_shard
-	                    FROM orders WHERE order_uid = $1`, orderUID).
-		Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.DeliveryService, &order.ShardKey, &order.SmID, &order.DateCreated, &order.OofShard)
+// selectOrders выбирает заказ вместе с доставкой и оплатой одним запросом.
+// LEFT JOIN вместо JOIN: заказ без строки delivery или payment не пропускается молча,
+// а возвращает ошибку сканирования NULL, как и при чтении заказа по частям.
+const selectOrders = `SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+                             d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+                             p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+                      FROM orders o
+                      LEFT JOIN delivery d ON d.order_uid = o.order_uid
+                      LEFT JOIN payment p ON p.order_uid = o.order_uid`
+
+// Функция для получения заказа из базы данных по его ID
+func GetOrderFromDB(ctx context.Context, db *sql.DB, orderUID string) (_ *Order, err error) {
+	defer observeQuery(ctx, "get_order", time.Now(), &err)
+	ctx, span := startOperation(ctx, "GetOrderFromDB", orderUID)
+	defer func() { endSpan(span, err) }()
+
+	// Получаем заказ, доставку и оплату одним запросом
+	query := selectOrders + ` WHERE o.order_uid = $1`
+	stmtCtx, stmtSpan := startStatement(ctx, "SELECT orders", query)
+	order, err := scanOrder(db.QueryRowContext(stmtCtx, query, orderUID))
+	endSpan(stmtSpan, err)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // если заказ не найден, возвращаем nil
 		}
-		return nil, fmt.Errorf("Ошибка получения order: %v", err) // возвращаем ошибку в случае неудачного запроса
+		return nil, err
 	}
 
-	// Получаем данные о доставке из таблицы delivery
-	err = db.QueryRow(`SELECT name, phone, zip, city, address, region, email
-	                    FROM delivery WHERE order_uid = $1`, orderUID).
-		Scan(&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email)
+	// Получаем данные о товарах из таблицы items
+	err = loadItems(ctx, db, []*Order{order})
 	if err != nil {
-		return nil, fmt.Errorf("Ошибка получения delivery: %v", err) // возвращаем ошибку в случае неудачного запроса
+		return nil, err
 	}
 
-	// Получаем данные об оплате из таблицы payment
-	err = db.QueryRow(`SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-	                    FROM payment WHERE order_uid = $1`, orderUID).
-		Scan(&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost, &order.Payment.GoodsTotal, &order.Payment.CustomFee)
+	return order, nil // возвращаем указатель на заказ
+}
+
+// Функция для получения всех заказов из базы данных
+func GetAllOrdersFromDB(db *sql.DB) ([]*Order, error) {
+	var orders []*Order
+	err := StreamOrdersFromDB(db, func(order *Order) error {
+		orders = append(orders, order) // добавляем заказ в срез всех заказов
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("Ошибка получения payment: %v", err) // возвращаем ошибку в случае неудачного запроса
+		return nil, err
 	}
+	return orders, nil // возвращаем срез всех заказов
+}
 
-	// Получаем данные о товарах из таблицы items
-	rows, err := db.Query(`SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-	                       FROM items WHERE order_uid = $1`, orderUID)
+// Функция для получения страницы заказов, упорядоченных по order_uid, начиная после afterUID.
+// На страницу выполняется два запроса: заказы с доставкой и оплатой, затем товары всех заказов страницы.
+func GetOrdersPageFromDB(db *sql.DB, afterUID string, limit int) ([]*Order, error) {
+	return queryOrders(context.Background(), db, selectOrders+` WHERE o.order_uid > $1 ORDER BY o.order_uid LIMIT $2`, afterUID, limit)
+}
+
+// Функция для получения страницы заказов, измененных после since, упорядоченных по order_uid, начиная после afterUID
+func GetUpdatedOrdersPageFromDB(db *sql.DB, since time.Time, afterUID string, limit int) ([]*Order, error) {
+	return queryOrders(context.Background(), db, selectOrders+` WHERE o.updated_at > $1 AND o.order_uid > $2 ORDER BY o.order_uid LIMIT $3`, since, afterUID, limit)
+}
+
+// Функция для получения текущего времени сервера базы данных
+func DBNow(db *sql.DB) (time.Time, error) {
+	var now time.Time
+	err := db.QueryRow(`SELECT now()`).Scan(&now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Ошибка получения времени БД: %v", err)
+	}
+	return now, nil
+}
+
+// queryOrders выполняет запрос на основе selectOrders и загружает товары найденных заказов
+func queryOrders(ctx context.Context, db *sql.DB, query string, args ...any) ([]*Order, error) {
+	stmtCtx, span := startStatement(ctx, "SELECT orders", query)
+	rows, err := db.QueryContext(stmtCtx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("Ошибка получения items: %v", err) // возвращаем ошибку в случае неудачного запроса
+		endSpan(span, err)
+		return nil, fmt.Errorf("Ошибка получения orders: %v", err) // возвращаем ошибку в случае неудачного запроса
 	}
 	defer rows.Close()
 
+	orders := make([]*Order, 0)
 	for rows.Next() {
-		var item Item
-		err := rows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
+		order, err := scanOrder(rows)
 		if err != nil {
-			return nil, fmt.Errorf("Ошибка сканирования item: %v", err) // возвращаем ошибку в случае неудачного сканирования строки
+			endSpan(span, err)
+			return nil, err
 		}
-		order.Items = append(order.Items, item) // добавляем товар в срез товаров заказа
+		orders = append(orders, order)
 	}
+	err = rows.Err()
+	endSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка итерации по строкам orders: %v", err) // возвращаем ошибку в случае ошибки итерации по строкам
+	}
+	rows.Close() // освобождаем соединение до запроса товаров
 
-	return &order, nil // возвращаем указатель на заказ
+	err = loadItems(ctx, db, orders)
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
 
-// Функция для получения всех заказов из базы данных
-func GetAllOrdersFromDB(db *sql.DB) ([]*Order, error) {
-	rows, err := db.Query(`SELECT order_uid, track_number, entry, locale, internal_signature, delivery_service, shardkey, sm_id, date_created, oof_shard FROM orders`)
+// scanOrder сканирует строку запроса selectOrders
+func scanOrder(row interface{ Scan(...any) error }) (*Order, error) {
+	var order Order
+	order.Items = make([]Item, 0) // инициализируем пустой срез для товаров в заказе
+	err := row.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SmID, &order.DateCreated, &order.OofShard,
+		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+		&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost, &order.Payment.GoodsTotal, &order.Payment.CustomFee)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("Ошибка получения orders: %v", err) // возвращаем ошибку в случае неудачного запроса
+		return nil, fmt.Errorf("Ошибка сканирования order %s: %v", order.OrderUID, err) // order_uid сканируется первым, поэтому известен и при отсутствии доставки или оплаты
 	}
-	defer rows.Close()
-
-	var orders []*Order
-	for rows.Next() {
-		var order Order
-		order.Items = make([]Item, 0) // инициализируем пустой срез для товаров в заказе
-
-		// Сканируем строку с данными о заказе
-		err := rows.Scan(&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature, &order.DeliveryService, &order.ShardKey, &order.SmID, &order.DateCreated, &order.OofShard)
-		if err != nil {
-			return nil, fmt.Errorf("Ошибка сканирования order: %v", err) // возвращаем ошибку в случае неудачного сканирования строки
-		}
+	return &order, nil
+}
 
-		// Получаем данные о доставке для текущего заказа
-		err = db.QueryRow(`SELECT name, phone, zip, city, address, region, email
-		                    FROM delivery WHERE order_uid = $1`, order.OrderUID).
-			Scan(&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip, &order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email)
-		if err != nil {
-			return nil, fmt.Errorf("Ошибка получения delivery: %v", err) // возвращаем ошибку в случае неудачного запроса
-		}
+// loadItems загружает товары для всех переданных заказов одним запросом
+func loadItems(ctx context.Context, db *sql.DB, orders []*Order) (err error) {
+	if len(orders) == 0 {
+		return nil
+	}
+	const query = `SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+	               FROM items WHERE order_uid = ANY($1) ORDER BY order_uid, id`
+	ctx, span := startStatement(ctx, "SELECT items", query)
+	defer func() { endSpan(span, err) }()
+
+	byUID := make(map[string]*Order, len(orders))
+	uids := make([]string, len(orders))
+	for i, order := range orders {
+		byUID[order.OrderUID] = order
+		uids[i] = order.OrderUID
+	}
 
-		// Получаем данные об оплате для текущего заказа
-		err = db.QueryRow(`SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-		                    FROM payment WHERE order_uid = $1`, order.OrderUID).
-			Scan(&order.Payment.Transaction, &order.Payment.RequestID, &order.Payment.Currency, &order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost, &order.Payment.GoodsTotal, &order.Payment.CustomFee)
-		if err != nil {
-			return nil, fmt.Errorf("Ошибка получения payment: %v", err) // возвращаем ошибку в случае неудачного запроса
-		}
+	rows, err := db.QueryContext(ctx, query, pq.Array(uids))
+	if err != nil {
+		return fmt.Errorf("Ошибка получения items: %v", err) // возвращаем ошибку в случае неудачного запроса
+	}
+	defer rows.Close()
 
-		// Получаем данные о товарах для текущего заказа
-		itemRows, err := db.Query(`SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-		                           FROM items WHERE order_uid = $1`, order.OrderUID)
+	for rows.Next() {
+		var orderUID string
+		var item Item
+		err := rows.Scan(&orderUID, &item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
 		if err != nil {
-			return nil, fmt.Errorf("Ошибка получения items: %v", err) // возвращаем ошибку в случае неудачного запроса
+			return fmt.Errorf("Ошибка сканирования item: %v", err) // возвращаем ошибку в случае неудачного сканирования строки
 		}
-		defer itemRows.Close()
-
-		for itemRows.Next() {
-			var item Item
-			err := itemRows.Scan(&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
-			if err != nil {
-				return nil, fmt.Errorf("Ошибка сканирования item: %v", err) // возвращаем ошибку в случае неудачного сканирования строки
-			}
+		if order, ok := byUID[orderUID]; ok {
 			order.Items = append(order.Items, item) // добавляем товар в срез товаров заказа
 		}
-
-		orders = append(orders, &order) // добавляем заказ в срез всех заказов
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("Ошибка итерации по строкам orders: %v", err) // возвращаем ошибку в случае ошибки итерации по строкам
+		return fmt.Errorf("Ошибка итерации по строкам items: %v", err)
 	}
-
-	return orders, nil // возвращаем срез всех заказов
+	return nil
 }
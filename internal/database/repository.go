@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"                 // импорт пакета для передачи контекста запроса
+	"database/sql"            // импорт стандартного пакета для работы с базой данных
+	"fmt"                     // импорт пакета для форматированного вывода
+	"time"                    // импорт пакета для работы со временем
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+
+	"github.com/lib/pq" // импорт драйвера PostgreSQL для передачи массивов
+)
+
+// OrderRepository описывает хранилище заказов, с которым работают обработчики.
+// Контекст операций над одним заказом отменяет запрос к БД и несет идентификатор запроса для логов.
+type OrderRepository interface {
+	Save(ctx context.Context, order *Order) error                   // сохраняет заказ согласно политике хранилища
+	Get(ctx context.Context, orderUID string) (*Order, error)       // возвращает заказ или nil, если его нет
+	List(ctx context.Context, opts ListOptions) (*OrderPage, error) // возвращает страницу заказов по фильтру
+	Delete(ctx context.Context, orderUID string) error              // удаляет заказ вместе с доставкой, оплатой и товарами
+	Exists(ctx context.Context, orderUID string) (bool, error)      // проверяет наличие заказа
+	Stream(fn func(order *Order) error) error                       // вызывает fn для каждого заказа, пока fn не вернет ошибку
+}
+
+// IngestRepository сохраняет заказы, полученные из nats-streaming, вместе с отметкой о сообщении.
+// Обработчик сообщений зависит только от него, поэтому проверяется без PostgreSQL.
+type IngestRepository interface {
+	SaveIngested(ctx context.Context, order *Order, msg IngestedMessage) error // ErrDuplicateMessage для повторной доставки
+}
+
+var (
+	_ OrderRepository  = (*PostgresRepository)(nil)
+	_ IngestRepository = (*PostgresRepository)(nil)
+)
+
+// PostgresRepository хранилище заказов в PostgreSQL
+type PostgresRepository struct {
+	db     *sql.DB
+	policy config.UpsertPolicy
+}
+
+// NewPostgresRepository создает хранилище заказов поверх подключения к PostgreSQL
+func NewPostgresRepository(db *sql.DB, policy config.UpsertPolicy) *PostgresRepository {
+	return &PostgresRepository{db: db, policy: policy}
+}
+
+func (r *PostgresRepository) Save(ctx context.Context, order *Order) error {
+	return SaveOrder(ctx, r.db, order, r.policy)
+}
+
+func (r *PostgresRepository) SaveIngested(ctx context.Context, order *Order, msg IngestedMessage) error {
+	return SaveIngestedOrder(ctx, r.db, order, r.policy, msg)
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, orderUID string) (*Order, error) {
+	return GetOrderFromDB(ctx, r.db, orderUID)
+}
+
+func (r *PostgresRepository) List(ctx context.Context, opts ListOptions) (*OrderPage, error) {
+	return ListOrders(ctx, r.db, opts)
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, orderUID string) error {
+	return DeleteOrder(ctx, r.db, orderUID)
+}
+
+func (r *PostgresRepository) Exists(ctx context.Context, orderUID string) (bool, error) {
+	return OrderExists(ctx, r.db, orderUID)
+}
+
+func (r *PostgresRepository) Stream(fn func(order *Order) error) error {
+	return StreamOrdersFromDB(r.db, fn)
+}
+
+// Функция для удаления заказа. Доставка, оплата и товары удаляются каскадно.
+func DeleteOrder(ctx context.Context, db *sql.DB, orderUID string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM orders WHERE order_uid = $1`, orderUID)
+	if err != nil {
+		return fmt.Errorf("Ошибка удаления order: %v", err)
+	}
+	return nil
+}
+
+// Функция для проверки наличия заказа в базе данных
+func OrderExists(ctx context.Context, db *sql.DB, orderUID string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE order_uid = $1)`, orderUID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка проверки order: %v", err)
+	}
+	return exists, nil
+}
+
+// Функция для получения UID заказов из orderUIDs, которые есть в базе данных
+func ExistingOrderUIDs(db *sql.DB, orderUIDs []string) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT order_uid FROM orders WHERE order_uid = ANY($1)`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка проверки orders: %v", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool, len(orderUIDs))
+	for rows.Next() {
+		var orderUID string
+		if err := rows.Scan(&orderUID); err != nil {
+			return nil, fmt.Errorf("Ошибка сканирования order_uid: %v", err)
+		}
+		existing[orderUID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Ошибка итерации по строкам orders: %v", err)
+	}
+	return existing, nil
+}
+
+// Функция для получения количества заказов в базе данных
+func CountOrders(db *sql.DB) (int64, error) {
+	var count int64
+	err := db.QueryRow(`SELECT count(*) FROM orders`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка подсчета orders: %v", err)
+	}
+	return count, nil
+}
+
+// Функция для получения наибольшего номера сообщения из subject, заказ из которого сохранен в базе данных.
+// Возвращает 0, если сообщений из subject еще не было.
+func LastIngestedSequence(db *sql.DB, subject string) (uint64, error) {
+	var sequence int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(sequence), 0) FROM ingested_messages WHERE subject = $1`, subject).Scan(&sequence)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка получения номера последнего сообщения из ingested_messages: %v", err)
+	}
+	return uint64(sequence), nil
+}
+
+// Функция для последовательной обработки всех заказов без загрузки их в память одним срезом.
+// Заказы читаются страницами по OrderBatchSize в порядке order_uid.
+func StreamOrdersFromDB(db *sql.DB, fn func(order *Order) error) error {
+	return streamPages(func(afterUID string) ([]*Order, error) {
+		return GetOrdersPageFromDB(db, afterUID, OrderBatchSize)
+	}, fn)
+}
+
+// Функция для последовательной обработки заказов, измененных после since
+func StreamOrdersUpdatedSince(db *sql.DB, since time.Time, fn func(order *Order) error) error {
+	return streamPages(func(afterUID string) ([]*Order, error) {
+		return GetUpdatedOrdersPageFromDB(db, since, afterUID, OrderBatchSize)
+	}, fn)
+}
+
+// streamPages запрашивает страницы заказов по ключу order_uid, пока не получит неполную страницу
+func streamPages(page func(afterUID string) ([]*Order, error), fn func(order *Order) error) error {
+	afterUID := ""
+	for {
+		orders, err := page(afterUID)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		if len(orders) < OrderBatchSize {
+			return nil
+		}
+		afterUID = orders[len(orders)-1].OrderUID
+	}
+}
+
+// Проверяем, что реализации соответствуют интерфейсу
+var (
+	_ OrderRepository = (*PostgresRepository)(nil)
+	_ OrderRepository = (*MemoryRepository)(nil)
+)
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"      // импорт пакета с контекстом трассировки
+	"database/sql" // импорт стандартного пакета для работы с базой данных
+	"errors"       // импорт пакета для работы с ошибками
+
+	"go.opentelemetry.io/otel"           // импорт API OpenTelemetry
+	"go.opentelemetry.io/otel/attribute" // импорт атрибутов spans
+	"go.opentelemetry.io/otel/codes"     // импорт статусов spans
+	"go.opentelemetry.io/otel/trace"     // импорт API трассировки
+)
+
+// tracer создает spans операций с заказами и SQL запросов
+var tracer = otel.Tracer("wb_test/internal/database")
+
+// startOperation начинает span операции с заказом, внутри которого создаются spans SQL запросов
+func startOperation(ctx context.Context, name, orderUID string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attribute.String("order.uid", orderUID)))
+}
+
+// startStatement начинает span SQL запроса. Запросы вне операции, например постраничная загрузка кэша,
+// не трассируются, чтобы не создавать отдельную трассу на каждую страницу.
+func startStatement(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx) // span без записи
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.query.text", query), // текст запроса с плейсхолдерами, значения не записываются
+	))
+}
+
+// endSpan завершает span и отмечает ошибку; отсутствие строки и отказ политики сохранения ошибкой не считаются
+func endSpan(span trace.Span, err error) {
+	switch {
+	case err == nil, errors.Is(err, sql.ErrNoRows):
+	case errors.Is(err, ErrOrderExists), errors.Is(err, ErrOrderNotNewer), errors.Is(err, ErrDuplicateMessage):
+		span.SetAttributes(attribute.String("order.rejected", err.Error()))
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// execTx выполняет SQL запрос в транзакции в отдельном span
+func execTx(ctx context.Context, tx *sql.Tx, name, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatement(ctx, name, query)
+	res, err := tx.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return res, err
+}
+
+// commitTx подтверждает транзакцию в отдельном span
+func commitTx(ctx context.Context, tx *sql.Tx) error {
+	_, span := startStatement(ctx, "COMMIT", "COMMIT")
+	err := tx.Commit()
+	endSpan(span, err)
+	return err
+}
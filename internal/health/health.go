@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context" // импорт пакета для ограничения времени проверки
+	"sync"    // импорт пакета для синхронизации goroutine
+	"time"    // импорт пакета для работы со временем
+)
+
+// Check проверяет зависимость сервиса и возвращает ошибку, если она недоступна
+type Check func(ctx context.Context) error
+
+// DependencyStatus результат проверки зависимости
+type DependencyStatus struct {
+	Name        string     `json:"name"`                    // имя зависимости
+	Ready       bool       `json:"ready"`                   // зависимость доступна
+	Error       string     `json:"error,omitempty"`         // ошибка текущей проверки
+	LastError   string     `json:"last_error,omitempty"`    // последняя ошибка, в том числе уже исправленная
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"` // время последней ошибки
+	CheckedAt   time.Time  `json:"checked_at"`              // время проверки
+}
+
+// Monitor проверяет зависимости сервиса и запоминает их последние ошибки
+type Monitor struct {
+	timeout time.Duration // время на проверку всех зависимостей
+
+	mu     sync.Mutex
+	checks []namedCheck
+	errors map[string]lastError // последняя ошибка по имени зависимости
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type lastError struct {
+	message string
+	at      time.Time
+}
+
+// NewMonitor создает монитор, который ограничивает проверку всех зависимостей временем timeout
+func NewMonitor(timeout time.Duration) *Monitor {
+	return &Monitor{timeout: timeout, errors: make(map[string]lastError)}
+}
+
+// Register добавляет проверку зависимости; зависимости проверяются в порядке регистрации
+func (m *Monitor) Register(name string, check Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, namedCheck{name: name, check: check})
+}
+
+// Check одновременно проверяет все зависимости и возвращает их состояние.
+// Проверка, не уложившаяся во время монитора, считается неуспешной.
+func (m *Monitor) Check(ctx context.Context) []DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	checks := append([]namedCheck(nil), m.checks...)
+	m.mu.Unlock()
+
+	statuses := make([]DependencyStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = m.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+// run выполняет одну проверку, не дольше чем позволяет ctx
+func (m *Monitor) run(ctx context.Context, c namedCheck) DependencyStatus {
+	result := make(chan error, 1)
+	go func() { result <- c.check(ctx) }()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err() // проверка не учитывает ctx и зависла
+	}
+
+	status := DependencyStatus{Name: c.name, Ready: err == nil, CheckedAt: time.Now()}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		status.Error = err.Error()
+		m.errors[c.name] = lastError{message: status.Error, at: status.CheckedAt}
+	}
+	if last, ok := m.errors[c.name]; ok {
+		status.LastError = last.message
+		status.LastErrorAt = &last.at
+	}
+	return status
+}
+
+// Ready сообщает, доступны ли все зависимости
+func Ready(statuses []DependencyStatus) bool {
+	for _, s := range statuses {
+		if !s.Ready {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"crypto/subtle"            // импорт пакета для сравнения токенов за постоянное время
+	"encoding/json"            // импорт пакета для работы с json
+	"errors"                   // импорт пакета для работы с ошибками
+	"log/slog"                 // импорт пакета структурированного логирования
+	"net/http"                 // импорт пакета для работы с http протоколом
+	"strings"                  // импорт пакета для работы со строками
+	"wb_test/internal/cache"   // импорт пакета для работы с кэшем
+	"wb_test/internal/logging" // импорт пакета для смены уровня логирования
+
+	"github.com/gorilla/mux" // импорт библиотеки gorilla/mux для маршрутизации http запросов
+)
+
+// adminStats статистика кэша вместе с ходом его загрузки
+type adminStats struct {
+	cache.Stats
+	Warmup cache.WarmupProgress `json:"warmup"`
+}
+
+// adminKeys страница UID заказов в кэше
+type adminKeys struct {
+	Total int      `json:"total"` // количество заказов в кэше
+	Keys  []string `json:"keys"`  // UID заказов на странице
+}
+
+// requireToken пропускает только запросы с заголовком Authorization: Bearer <token>
+func requireToken(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				audit(r, "отказ в доступе")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Требуется авторизация", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// audit записывает в лог действие администратора
+func audit(r *http.Request, action string) {
+	slog.InfoContext(r.Context(), "Аудит", "method", r.Method, "uri", r.URL.RequestURI(), "remote_addr", r.RemoteAddr, "action", action)
+}
+
+func (s *Server) adminStatsHandler(w http.ResponseWriter, r *http.Request) {
+	audit(r, "просмотр статистики кэша")
+	writeJSON(w, http.StatusOK, adminStats{Stats: s.Cache.Stats(), Warmup: s.Warmup.Status()})
+}
+
+func (s *Server) adminKeysHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", 100) // получаем размер страницы из параметров запроса
+	if err != nil || limit <= 0 || limit > 10000 {
+		http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0) // получаем смещение из параметров запроса
+	if err != nil || offset < 0 {
+		http.Error(w, "Некорректный параметр offset", http.StatusBadRequest)
+		return
+	}
+
+	audit(r, "просмотр списка заказов в кэше")
+	keys := s.Cache.Keys()
+	page := adminKeys{Total: len(keys), Keys: []string{}}
+	if offset < len(keys) {
+		page.Keys = keys[offset:min(offset+limit, len(keys))]
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) adminEntryHandler(w http.ResponseWriter, r *http.Request) {
+	orderUID := mux.Vars(r)["id"]
+	audit(r, "просмотр заказа "+orderUID+" в кэше")
+	info, found := s.Cache.Peek(orderUID)
+	if !found {
+		http.NotFound(w, r) // возвращаем ошибку 404, если заказа нет в кэше
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) adminEvictHandler(w http.ResponseWriter, r *http.Request) {
+	orderUID := mux.Vars(r)["id"]
+	if !s.Cache.Delete(orderUID) {
+		audit(r, "заказ "+orderUID+" не найден в кэше")
+		http.NotFound(w, r)
+		return
+	}
+	audit(r, "заказ "+orderUID+" удален из кэша")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) adminFlushHandler(w http.ResponseWriter, r *http.Request) {
+	s.Cache.Purge()
+	audit(r, "кэш очищен")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) adminResyncHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.Resync() // очищаем кэш и загружаем его заново в фоне
+	if errors.Is(err, cache.ErrWarmupRunning) {
+		audit(r, "повторная загрузка кэша отклонена: "+err.Error())
+		http.Error(w, err.Error(), http.StatusConflict) // возвращаем http код 409, загрузка уже выполняется
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Не удалось запустить повторную загрузку кэша", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	audit(r, "запущена повторная загрузка кэша из базы данных")
+	writeJSON(w, http.StatusAccepted, s.Warmup.Status()) // ход загрузки доступен в GET /admin/cache
+}
+
+// logLevel тело запроса и ответа /admin/log-level
+type logLevel struct {
+	Level string `json:"level"` // debug, info, warn или error
+}
+
+func (s *Server) getLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevel{Level: strings.ToLower(logging.Level().String())})
+}
+
+func (s *Server) setLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var req logLevel
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := logging.SetLevel(req.Level); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	audit(r, "уровень логирования изменен на "+req.Level)
+	writeJSON(w, http.StatusOK, logLevel{Level: strings.ToLower(logging.Level().String())})
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"encoding/json" // импорт пакета для работы с json
+	"log/slog"      // импорт пакета структурированного логирования
+	"net/http"      // импорт пакета для работы с http протоколом
+	"strconv"       // импорт пакета для преобразования строк в числа
+
+	"github.com/gorilla/mux" // импорт библиотеки gorilla/mux для маршрутизации http запросов
+)
+
+func (s *Server) listDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", 50) // получаем размер страницы из параметров запроса
+	if err != nil || limit <= 0 || limit > 1000 {
+		http.Error(w, "Некорректный параметр limit", http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0) // получаем смещение из параметров запроса
+	if err != nil || offset < 0 {
+		http.Error(w, "Некорректный параметр offset", http.StatusBadRequest)
+		return
+	}
+
+	deadLetters, err := s.DeadLetters.List(limit, offset) // получаем dead letters из базы данных
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка получения dead letters из БД", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deadLetters) // отправляем json ответа со списком dead letters
+}
+
+func (s *Server) replayDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64) // получаем ID dead letter из URL
+	if err != nil {
+		http.Error(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+
+	dl, err := s.DeadLetters.Replay(id, s.OrdersChannel) // отправляем сообщение обратно в канал заказов
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка повторной отправки dead letter", "id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if dl == nil {
+		http.NotFound(w, r) // возвращаем ошибку 404, если dead letter не найден
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Dead letter отправлен повторно", "id", id, "channel", s.OrdersChannel)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dl) // отправляем json ответа с отправленным dead letter
+}
+
+// queryInt возвращает целочисленный параметр запроса или значение по умолчанию
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"                                    // импорт пакета для работы с http протоколом
+	"time"                                        // импорт пакета для работы со временем
+	"wb_test/internal/cache"                      // импорт пакета для работы с кэшем
+	"wb_test/internal/health"                     // импорт пакета для проверки зависимостей
+	subscriber "wb_test/internal/nats/subscriber" // импорт пакета для получения состояния приема заказов
+)
+
+// startedAt время запуска процесса
+var startedAt = time.Now()
+
+// readiness ответ /readyz
+type readiness struct {
+	Ready        bool                      `json:"ready"`
+	Dependencies []health.DependencyStatus `json:"dependencies"`
+}
+
+// status ответ /status
+type status struct {
+	readiness
+	StartedAt time.Time               `json:"started_at"` // время запуска процесса
+	Uptime    string                  `json:"uptime"`     // время работы процесса
+	Ingest    subscriber.IngestStatus `json:"ingest"`     // прием заказов из nats-streaming
+	Warmup    cache.WarmupProgress    `json:"warmup"`     // загрузка кэша из базы данных
+	Cache     cache.Stats             `json:"cache"`      // статистика кэша
+}
+
+// healthzHandler сообщает, что процесс жив и обрабатывает запросы; зависимости не проверяются.
+// 503 возвращается, только если процесс не может восстановиться без перезапуска.
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if s.Alive != nil {
+		if err := s.Alive(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler проверяет зависимости и возвращает 503, если сервис не готов принимать трафик
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	result := s.readiness(r)
+	writeJSON(w, readinessCode(result.Ready), result)
+}
+
+// statusHandler возвращает подробное состояние зависимостей, приема заказов и кэша; код ответа всегда 200
+func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
+	result := status{
+		readiness: s.readiness(r),
+		StartedAt: startedAt,
+		Uptime:    time.Since(startedAt).Round(time.Second).String(),
+		Ingest:    subscriber.Status(),
+		Warmup:    s.Warmup.Status(),
+		Cache:     s.Cache.Stats(),
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readiness проверяет все зависимости сервиса
+func (s *Server) readiness(r *http.Request) readiness {
+	dependencies := s.Health.Check(r.Context())
+	return readiness{Ready: health.Ready(dependencies), Dependencies: dependencies}
+}
+
+// readinessCode возвращает 200 для готового сервиса и 503 для неготового
+func readinessCode(ready bool) int {
+	if ready {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
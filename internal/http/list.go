@@ -0,0 +1,103 @@
+package http
+
+import (
+	"errors"                    // импорт пакета для работы с ошибками
+	"fmt"                       // импорт пакета для форматированного вывода
+	"log/slog"                  // импорт пакета структурированного логирования
+	"net/http"                  // импорт пакета для работы с http протоколом
+	"strconv"                   // импорт пакета для преобразования строк в числа
+	"time"                      // импорт пакета для работы со временем
+	"wb_test/internal/database" // импорт локального пакета для работы с базой данных
+)
+
+// listOrdersHandler возвращает страницу заказов из БД по фильтрам из параметров запроса.
+// Следующая страница запрашивается с параметром cursor из next_cursor ответа и теми же фильтрами и сортировкой.
+func (s *Server) listOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := s.Orders.List(r.Context(), opts) // получаем страницу заказов из хранилища
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, "Некорректный параметр cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка получения списка заказов из БД", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page) // отправляем json ответа со страницей заказов
+}
+
+// parseListOptions разбирает фильтры, сортировку и пагинацию списка заказов:
+// sort=date_created|amount, order=desc|asc, limit, cursor, date_from и date_to в RFC 3339, status товара числом
+func parseListOptions(r *http.Request) (database.ListOptions, error) {
+	q := r.URL.Query()
+	opts := database.ListOptions{
+		Filter: database.OrderFilter{
+			TrackNumber:     q.Get("track_number"),
+			CustomerID:      q.Get("customer_id"),
+			DeliveryService: q.Get("delivery_service"),
+			Entry:           q.Get("entry"),
+			Locale:          q.Get("locale"),
+			PaymentProvider: q.Get("payment_provider"),
+			PaymentBank:     q.Get("payment_bank"),
+			ItemBrand:       q.Get("brand"),
+		},
+		Sort:   database.SortDateCreated,
+		Desc:   true, // по умолчанию сначала новые заказы
+		Cursor: q.Get("cursor"),
+	}
+
+	var err error
+	if opts.Filter.CreatedFrom, err = queryTime(r, "date_from"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.CreatedTo, err = queryTime(r, "date_to"); err != nil {
+		return opts, err
+	}
+	if value := q.Get("status"); value != "" {
+		status, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, errors.New("Некорректный параметр status")
+		}
+		opts.Filter.ItemStatus = &status
+	}
+
+	if value := q.Get("sort"); value != "" {
+		opts.Sort = database.ListSort(value)
+		if !opts.Sort.Valid() {
+			return opts, errors.New("Некорректный параметр sort: ожидается date_created или amount")
+		}
+	}
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Desc = false
+	default:
+		return opts, errors.New("Некорректный параметр order: ожидается asc или desc")
+	}
+
+	opts.Limit, err = queryInt(r, "limit", database.DefaultListLimit) // получаем размер страницы из параметров запроса
+	if err != nil || opts.Limit <= 0 || opts.Limit > database.MaxListLimit {
+		return opts, fmt.Errorf("Некорректный параметр limit: ожидается число от 1 до %d", database.MaxListLimit)
+	}
+	return opts, nil
+}
+
+// queryTime возвращает параметр запроса со временем в RFC 3339 или нулевое время, если параметр не задан
+func queryTime(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Некорректный параметр %s: ожидается время в RFC 3339", name)
+	}
+	return t, nil
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http" // импорт пакета для работы с http протоколом
+	"strconv"  // импорт пакета для преобразования чисел в строки
+	"time"     // импорт пакета для работы со временем
+
+	"github.com/gorilla/mux"                                  // импорт библиотеки gorilla/mux для маршрутизации http запросов
+	"github.com/prometheus/client_golang/prometheus"          // импорт библиотеки метрик prometheus
+	"github.com/prometheus/client_golang/prometheus/promauto" // импорт пакета для регистрации метрик при объявлении
+)
+
+var (
+	// httpRequests количество запросов по шаблону маршрута, методу и коду ответа
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_http_requests_total",
+		Help: "Количество http запросов по шаблону маршрута, методу и коду ответа.",
+	}, []string{"route", "method", "status"})
+	// httpDuration длительность обработки запросов по шаблону маршрута, методу и коду ответа
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "orders_http_request_duration_seconds",
+		Help:    "Длительность обработки http запросов по шаблону маршрута, методу и коду ответа.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// Metrics считает запросы и их длительность. Маршрут определяется по шаблону router, например /orders/{id},
+// чтобы количество рядов метрик не зависело от ID; запросы к неизвестным адресам учитываются как unmatched.
+func Metrics(router *mux.Router) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(router, r)
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			status := strconv.Itoa(rec.status)
+			httpRequests.WithLabelValues(route, r.Method, status).Inc()
+			httpDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// routeTemplate возвращает шаблон маршрута router, которому соответствует запрос, или unmatched
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router.Match(r, &match) && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
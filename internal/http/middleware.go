@@ -0,0 +1,90 @@
+package http
+
+import (
+	"log/slog"                 // импорт пакета структурированного логирования
+	"net/http"                 // импорт пакета для работы с http протоколом
+	"time"                     // импорт пакета для работы со временем
+	"wb_test/internal/logging" // импорт пакета для передачи идентификатора запроса в логи
+)
+
+// Middleware оборачивает обработчик дополнительной логикой
+type Middleware func(next http.Handler) http.Handler
+
+// Chain оборачивает h цепочкой middleware так, что первый из них получает запрос первым
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// RequestIDHeader заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID берет идентификатор запроса из заголовка X-Request-ID или генерирует новый,
+// возвращает его в ответе и кладет в контекст запроса, откуда он попадает в логи обработчиков и запросов к БД
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID проверяет, что идентификатор от клиента не пустой, не длиннее 128 символов и состоит из печатных ASCII символов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Recover перехватывает панику обработчика, пишет ее в лог и возвращает 500
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p) // обработчик сам прервал ответ, net/http обработает это без лишнего лога
+				}
+				slog.ErrorContext(r.Context(), "Паника при обработке запроса", "method", r.Method, "path", r.URL.Path, "panic", p)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LogRequests пишет в лог метод, путь, код ответа и время обработки каждого запроса
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		slog.InfoContext(r.Context(), "Http запрос", "method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", time.Since(start))
+	})
+}
+
+// statusRecorder запоминает код ответа, отправленный обработчиком
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"               // импорт пакета для работы с json
+	"errors"                      // импорт пакета для работы с ошибками
+	"log/slog"                    // импорт пакета структурированного логирования
+	"net/http"                    // импорт пакета для работы с http протоколом
+	"strconv"                     // импорт пакета для преобразования строк в числа
+	"strings"                     // импорт пакета для работы со строками
+	"wb_test/internal/cache"      // импорт пакета для работы с кэшем
+	"wb_test/internal/database"   // импорт локального пакета для работы с базой данных
+	"wb_test/internal/validation" // импорт пакета для проверки заказов
+
+	"github.com/gorilla/mux" // импорт библиотеки gorilla/mux для маршрутизации http запросов
+)
+
+func (s *Server) getOrderHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)    // получаем переменные из URL
+	orderUID := vars["id"] // получаем ID заказа из переменных
+	s.serveOrder(w, r, orderUID)
+}
+
+// getOrderByQueryHandler обрабатывает совместимый адрес /order?order_uid=
+func (s *Server) getOrderByQueryHandler(w http.ResponseWriter, r *http.Request) {
+	orderUID := r.URL.Query().Get("order_uid")
+	if orderUID == "" {
+		http.Error(w, "Требуется order_uid", http.StatusBadRequest)
+		return
+	}
+	s.serveOrder(w, r, orderUID)
+}
+
+// serveOrder отправляет заказ из кэша, а при промахе читает его из БД и сохраняет в кэш
+func (s *Server) serveOrder(w http.ResponseWriter, r *http.Request, orderUID string) {
+	slog.DebugContext(r.Context(), "Получение заказа", "order_uid", orderUID) // логируем получение заказа по ID
+
+	encoded, found := s.lookupCache(r, orderUID) // получаем готовый JSON заказа из кэша
+	if found {
+		slog.DebugContext(r.Context(), "Заказ найден в кэше", "order_uid", orderUID) // логируем нахождение заказа в кэше
+		writeEncodedOrder(w, r, encoded)                                             // отпраляем json ответа с найденным заказом
+		return
+	}
+
+	order, err := s.Orders.Get(r.Context(), orderUID) // получаем заказ из базы данных
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка получения заказа из БД", "order_uid", orderUID, "error", err) // логируем ошибку получения заказа из БД
+		http.Error(w, err.Error(), http.StatusInternalServerError)                                           // возвращаем http ошибки в случае ошибки БД
+		return
+	}
+	if order == nil {
+		slog.DebugContext(r.Context(), "Заказ не найден в БД", "order_uid", orderUID) // логируем отсутствие заказа в БД
+		http.NotFound(w, r)                                                           // возвращаем ошибку 404
+		return
+	}
+
+	s.Cache.Fill(order)                                                                            // сохраняем заказ в кэш, если подписчик не записал более новую версию
+	slog.DebugContext(r.Context(), "Заказ получен из БД и сохранен в кэше", "order_uid", orderUID) // данные заказа не логируем: в них телефон, email и адрес
+	writeEncodedOrder(w, r, cache.Encode(order))                                                   // отпраляем json ответа с найденным заказом
+}
+
+// writeEncodedOrder отправляет заказ в JSON с ETag или 304, если у клиента актуальная версия
+func writeEncodedOrder(w http.ResponseWriter, r *http.Request, encoded cache.Encoded) {
+	w.Header().Set("ETag", encoded.ETag)
+	if etagMatches(r.Header.Get("If-None-Match"), encoded.ETag) {
+		w.WriteHeader(http.StatusNotModified) // возвращаем http код 304, заказ не изменился
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(encoded.Len()))
+	encoded.WriteTo(w)
+}
+
+// etagMatches проверяет заголовок If-None-Match: список ETag через запятую или *.
+// Для GET допускается слабое сравнение, поэтому префикс W/ не учитывается.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) createOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var order database.Order                      // объявляем переменную для нового заказа типа database.Order
+	err := json.NewDecoder(r.Body).Decode(&order) // декодируем JSON тела запроса в структуру заказа
+	if err != nil {
+		slog.WarnContext(r.Context(), "Ошибка получения заказа из JSON", "error", err) // логируем ошибку декодирования JSON
+		http.Error(w, err.Error(), http.StatusBadRequest)                              // возвращаем http ошибки 400 в случае некорректного запроса
+		return
+	}
+
+	if errs := validation.Validate(&order); len(errs) > 0 {
+		slog.WarnContext(r.Context(), "Заказ не прошел проверку", "order_uid", order.OrderUID, "errors", errs) // логируем ошибки проверки заказа
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity) // возвращаем http код 422 со списком ошибок полей
+		json.NewEncoder(w).Encode(errs)
+		return
+	}
+
+	err = s.Orders.Save(r.Context(), &order) // сохраняем заказ в БД
+	if errors.Is(err, database.ErrOrderExists) || errors.Is(err, database.ErrOrderNotNewer) {
+		slog.InfoContext(r.Context(), "Заказ не сохранен", "order_uid", order.OrderUID, "reason", err) // логируем отказ политики сохранения
+		http.Error(w, err.Error(), http.StatusConflict)                                                // возвращаем http код 409, заказ уже есть в БД
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка сохранения заказа в БД", "order_uid", order.OrderUID, "error", err) // логируем ошибку сохранения заказа в БД
+		http.Error(w, err.Error(), http.StatusInternalServerError)                                                 // возвращаем http ошибки в случае ошибки сохранения заказа в БД
+		return
+	}
+
+	s.Cache.Set(&order) // сохраняем заказ в кэш
+
+	writeJSON(w, http.StatusCreated, order) // отправляем json ответа с созданным заказом и HTTP код 201
+}
@@ -1,29 +1,129 @@
 package http
 
 import (
-	"encoding/json"
-	"log"
-	"net/http"
-	"wb_test/internal/cache"
+	"context"                                     // импорт пакета для ограничения времени остановки
+	"encoding/json"                               // импорт пакета для работы с json
+	"expvar"                                      // импорт пакета для публикации счетчиков
+	"log/slog"                                    // импорт пакета структурированного логирования
+	"net/http"                                    // импорт пакета для работы с http протоколом
+	"time"                                        // импорт пакета для работы со временем
+	"wb_test/internal/cache"                      // импорт пакета для работы с кэшем
+	"wb_test/internal/config"                     // импорт пакета с настройками сервиса
+	"wb_test/internal/database"                   // импорт локального пакета для работы с базой данных
+	"wb_test/internal/health"                     // импорт пакета для проверки зависимостей
+	subscriber "wb_test/internal/nats/subscriber" // импорт пакета для работы с dead-letter очередью
+
+	"github.com/gorilla/mux"                                  // импорт библиотеки gorilla/mux для маршрутизации http запросов
+	"github.com/prometheus/client_golang/prometheus/promhttp" // импорт обработчика метрик prometheus
 )
 
-func StartServer() {
-	http.HandleFunc("/order", getOrderHandler)
-	log.Fatal(http.ListenAndServe(":8000", nil))
+// Deps зависимости http обработчиков
+type Deps struct {
+	Orders        database.OrderRepository // хранилище заказов
+	Cache         cache.OrderCache         // кэш заказов
+	Warmup        *cache.Warmup            // ход загрузки кэша из базы данных
+	Resync        func() error             // запускает повторную загрузку кэша в фоне, cache.ErrWarmupRunning, если она уже идет
+	DeadLetters   subscriber.DeadLetters   // dead-letter очередь
+	OrdersChannel string                   // канал, в который повторно отправляются dead letters
+	Health        *health.Monitor          // проверка зависимостей для /readyz и /status
+	Alive         func() error             // ошибка, после которой процесс нужно перезапустить, для /healthz
 }
 
-func getOrderHandler(w http.ResponseWriter, r *http.Request) {
-	orderUID := r.URL.Query().Get("order_uid")
-	if orderUID == "" {
-		http.Error(w, "Требуется order_uid", http.StatusBadRequest)
-		return
+// Server http сервер сервиса заказов: все маршруты на одном роутере gorilla/mux
+// и цепочка middleware, через которую проходит каждый запрос
+type Server struct {
+	Deps
+	router     *mux.Router
+	middleware []Middleware
+	server     *http.Server
+}
+
+// New создает сервер с маршрутами и middleware по умолчанию: идентификатор запроса, журнал запросов,
+// метрики и восстановление после паники.
+// Маршруты /admin/cache добавляются, только если задан cfg.AdminToken.
+func New(cfg config.HTTPConfig, deps Deps) *Server {
+	s := &Server{Deps: deps, router: mux.NewRouter()}
+	s.routes(cfg.AdminToken)
+	s.Use(RequestID, Tracing(s.router), LogRequests, Metrics(s.router), Recover)
+	s.server = &http.Server{
+		Addr:              cfg.Addr,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+	return s
+}
+
+// routes регистрирует все маршруты сервера
+func (s *Server) routes(adminToken string) {
+	r := s.router
+	r.HandleFunc("/orders/{id}", s.getOrderHandler).Methods("GET")  // заказ по ID: из кэша или из БД
+	r.HandleFunc("/order", s.getOrderByQueryHandler).Methods("GET") // совместимый адрес /order?order_uid=
+	r.HandleFunc("/orders", s.listOrdersHandler).Methods("GET")     // список заказов из БД с фильтрами
+	r.HandleFunc("/orders", s.createOrderHandler).Methods("POST")   // создание заказа
+	r.Handle("/debug/vars", expvar.Handler()).Methods("GET")        // счетчики сервиса
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")         // метрики prometheus
+	r.HandleFunc("/healthz", s.healthzHandler).Methods("GET")       // процесс жив
+	r.HandleFunc("/readyz", s.readyzHandler).Methods("GET")         // зависимости доступны, кэш загружен
+	r.HandleFunc("/status", s.statusHandler).Methods("GET")         // подробное состояние зависимостей
 
-	order, found := cache.GetOrderFromCache(orderUID)
-	if !found {
-		http.Error(w, "Заказ не найден", http.StatusNotFound)
+	if adminToken == "" {
+		slog.Warn("Токен администратора не задан, маршруты /admin и /dead-letters отключены")
 		return
 	}
+	logLevel := r.PathPrefix("/admin/log-level").Subrouter()
+	logLevel.Use(requireToken(adminToken))
+	logLevel.HandleFunc("", s.getLogLevelHandler).Methods("GET") // текущий уровень логирования
+	logLevel.HandleFunc("", s.setLogLevelHandler).Methods("PUT") // смена уровня логирования без перезапуска
+
+	admin := r.PathPrefix("/admin/cache").Subrouter()
+	admin.Use(requireToken(adminToken))
+	admin.HandleFunc("", s.adminStatsHandler).Methods("GET")                 // статистика кэша
+	admin.HandleFunc("", s.adminFlushHandler).Methods("DELETE")              // удаление всех заказов из кэша
+	admin.HandleFunc("/keys", s.adminKeysHandler).Methods("GET")             // список UID заказов
+	admin.HandleFunc("/entries/{id}", s.adminEntryHandler).Methods("GET")    // запись кэша как она хранится
+	admin.HandleFunc("/entries/{id}", s.adminEvictHandler).Methods("DELETE") // удаление одного заказа из кэша
+	admin.HandleFunc("/resync", s.adminResyncHandler).Methods("POST")        // повторная загрузка кэша из базы данных
+
+	deadLetters := r.PathPrefix("/dead-letters").Subrouter() // в сообщениях телефоны и email покупателей, повтор отправляет их в канал заказов
+	deadLetters.Use(requireToken(adminToken))
+	deadLetters.HandleFunc("", s.listDeadLettersHandler).Methods("GET")               // список dead letters
+	deadLetters.HandleFunc("/{id}/replay", s.replayDeadLetterHandler).Methods("POST") // повторная отправка dead letter
+}
+
+// Use добавляет middleware в конец цепочки; первый добавленный middleware получает запрос первым
+func (s *Server) Use(middleware ...Middleware) {
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// Handler возвращает роутер, обернутый цепочкой middleware
+func (s *Server) Handler() http.Handler {
+	return Chain(s.router, s.middleware...)
+}
+
+// Router возвращает роутер для регистрации дополнительных маршрутов
+func (s *Server) Router() *mux.Router {
+	return s.router
+}
+
+// Addr возвращает адрес, на котором слушает сервер
+func (s *Server) Addr() string {
+	return s.server.Addr
+}
+
+// ListenAndServe запускает сервер и блокируется до его остановки.
+// Middleware, добавленные после запуска, не применяются.
+func (s *Server) ListenAndServe() error {
+	s.server.Handler = s.Handler()
+	return s.server.ListenAndServe()
+}
+
+// Shutdown перестает принимать соединения и ждет завершения текущих запросов, пока не истечет ctx
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
 
-	json.NewEncoder(w).Encode(order)
+// writeJSON отправляет значение в JSON с переданным http кодом
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
 }
@@ -0,0 +1,268 @@
+package http
+
+import (
+	"bytes"                                       // импорт пакета для тела запроса
+	"context"                                     // импорт пакета для вызова методов хранилища
+	"encoding/json"                               // импорт пакета для работы с json
+	"errors"                                      // импорт пакета для работы с ошибками
+	"net/http"                                    // импорт пакета для работы с http протоколом
+	"net/http/httptest"                           // импорт пакета для проверки обработчиков без сети
+	"testing"                                     // импорт пакета для тестов
+	"time"                                        // импорт пакета для работы со временем
+	"wb_test/internal/cache"                      // импорт пакета для работы с кэшем
+	"wb_test/internal/config"                     // импорт пакета с настройками сервиса
+	"wb_test/internal/database"                   // импорт локального пакета для работы с базой данных
+	"wb_test/internal/health"                     // импорт пакета для проверки зависимостей
+	subscriber "wb_test/internal/nats/subscriber" // импорт пакета с интерфейсом dead-letter очереди
+	"wb_test/internal/ordertest"                  // импорт пакета с заказом для тестов
+
+	"github.com/nats-io/stan.go"
+)
+
+// testAdminToken токен администратора тестового сервера
+const testAdminToken = "secret"
+
+// fakeDeadLetters dead-letter очередь в памяти
+type fakeDeadLetters struct {
+	letters  []*database.DeadLetter
+	replayed []string // каналы, в которые отправлялись сообщения
+}
+
+func (q *fakeDeadLetters) Send(*stan.Msg, string, error) error {
+	return nil
+}
+
+func (q *fakeDeadLetters) List(limit, offset int) ([]*database.DeadLetter, error) {
+	return q.letters[min(offset, len(q.letters)):min(offset+limit, len(q.letters))], nil
+}
+
+func (q *fakeDeadLetters) Replay(id int64, channel string) (*database.DeadLetter, error) {
+	for _, dl := range q.letters {
+		if dl.ID == id {
+			q.replayed = append(q.replayed, channel)
+			return dl, nil
+		}
+	}
+	return nil, nil
+}
+
+var _ subscriber.DeadLetters = (*fakeDeadLetters)(nil)
+
+// testServer сервер с хранилищем заказов в памяти
+type testServer struct {
+	*Server
+	orders *database.MemoryRepository
+	cache  *cache.Cache
+	dlq    *fakeDeadLetters
+	resync error // ошибка, которую возвращает Resync
+}
+
+func newTestServer(t *testing.T, policy config.UpsertPolicy) *testServer {
+	ts := &testServer{
+		orders: database.NewMemoryRepository(policy),
+		cache:  cache.New(config.CacheConfig{}),
+		dlq:    &fakeDeadLetters{},
+	}
+	t.Cleanup(ts.cache.Close)
+	ts.Server = New(config.HTTPConfig{AdminToken: testAdminToken}, Deps{
+		Orders:        ts.orders,
+		Cache:         ts.cache,
+		Warmup:        &cache.Warmup{},
+		Resync:        func() error { return ts.resync },
+		DeadLetters:   ts.dlq,
+		OrdersChannel: "orders",
+		Health:        health.NewMonitor(time.Second),
+	})
+	return ts
+}
+
+// do выполняет запрос через всю цепочку middleware
+func (ts *testServer) do(method, target string, body []byte, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	ts.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func TestCreateAndGetOrder(t *testing.T) {
+	ts := newTestServer(t, config.UpsertReplace)
+	body, _ := json.Marshal(ordertest.ValidOrder("a"))
+	if w := ts.do("POST", "/orders", body, nil); w.Code != http.StatusCreated || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("POST /orders: %d, Content-Type %q, %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+
+	w := ts.do("GET", "/orders/a", nil, nil)
+	etag := w.Header().Get("ETag")
+	var got database.Order
+	if w.Code != http.StatusOK || etag == "" || json.Unmarshal(w.Body.Bytes(), &got) != nil || got.Payment.Amount != 1817 {
+		t.Fatalf("GET /orders/a: %d, ETag %q, %s", w.Code, etag, w.Body)
+	}
+	if w := ts.do("GET", "/orders/a", nil, http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("GET с актуальным ETag: %d %s, ожидается 304 без тела", w.Code, w.Body)
+	}
+
+	ts.cache.Delete("a") // следующий запрос читает заказ из хранилища и возвращает его в кэш
+	if w := ts.do("GET", "/order?order_uid=a", nil, nil); w.Code != http.StatusOK || w.Header().Get("ETag") != etag {
+		t.Errorf("GET при промахе кэша: %d, ETag %q, ожидается 200 и %q", w.Code, w.Header().Get("ETag"), etag)
+	}
+	if _, ok := ts.cache.Get("a"); !ok {
+		t.Error("заказ из хранилища не сохранен в кэш")
+	}
+}
+
+func TestCreateOrderErrors(t *testing.T) {
+	ts := newTestServer(t, config.UpsertReject)
+	valid, _ := json.Marshal(ordertest.ValidOrder("a"))
+	invalidOrder := ordertest.ValidOrder("b")
+	invalidOrder.Delivery.Email = "not an email"
+	invalid, _ := json.Marshal(invalidOrder)
+	tests := []struct {
+		name string
+		body []byte
+		want int
+	}{
+		{"новый заказ", valid, http.StatusCreated},
+		{"повторный заказ", valid, http.StatusConflict},
+		{"некорректный JSON", []byte("{"), http.StatusBadRequest},
+		{"заказ не прошел проверку", invalid, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		if w := ts.do("POST", "/orders", tt.body, nil); w.Code != tt.want {
+			t.Errorf("%s: %d %s, ожидается %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+	if exists, _ := ts.orders.Exists(context.Background(), "b"); exists {
+		t.Error("заказ, не прошедший проверку, сохранен")
+	}
+}
+
+func TestGetOrderNotFound(t *testing.T) {
+	ts := newTestServer(t, config.UpsertReplace)
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/orders/missing", http.StatusNotFound},
+		{"/order?order_uid=missing", http.StatusNotFound},
+		{"/order", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := ts.do("GET", tt.target, nil, nil); w.Code != tt.want {
+			t.Errorf("GET %s: %d, ожидается %d", tt.target, w.Code, tt.want)
+		}
+	}
+	if ts.cache.Len() != 0 {
+		t.Error("отсутствующий заказ сохранен в кэш")
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	ts := newTestServer(t, config.UpsertReplace)
+	for i, uid := range []string{"a", "b", "c"} {
+		if err := ts.orders.Save(context.Background(), &database.Order{OrderUID: uid, Payment: database.Payment{Amount: 3000 - i*100}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var uids []string
+	target := "/orders?sort=amount&order=asc&limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("курсор не продвигается")
+		}
+		w := ts.do("GET", target, nil, nil)
+		var page database.OrderPage
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &page) != nil {
+			t.Fatalf("GET %s: %d %s", target, w.Code, w.Body)
+		}
+		for _, order := range page.Orders {
+			uids = append(uids, order.OrderUID)
+		}
+		target = ""
+		if page.NextCursor != "" {
+			target = "/orders?sort=amount&order=asc&limit=2&cursor=" + page.NextCursor
+		}
+	}
+	if len(uids) != 3 || uids[0] != "c" || uids[1] != "b" || uids[2] != "a" {
+		t.Errorf("заказы %v, ожидается [c b a]", uids)
+	}
+
+	for _, target := range []string{
+		"/orders?cursor=broken",
+		"/orders?sort=price",
+		"/orders?order=up",
+		"/orders?limit=0",
+		"/orders?date_from=yesterday",
+		"/orders?status=ok",
+	} {
+		if w := ts.do("GET", target, nil, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: %d, ожидается 400", target, w.Code)
+		}
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	ts := newTestServer(t, config.UpsertReplace)
+	ts.dlq.letters = []*database.DeadLetter{{ID: 2, Stage: database.StageValidate}, {ID: 1, Stage: database.StageDecode}}
+	auth := http.Header{"Authorization": {"Bearer " + testAdminToken}}
+
+	for _, r := range []struct{ method, target string }{{"GET", "/dead-letters"}, {"POST", "/dead-letters/2/replay"}} {
+		if w := ts.do(r.method, r.target, nil, nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s без токена: %d, ожидается 401", r.method, r.target, w.Code)
+		}
+	}
+	if len(ts.dlq.replayed) != 0 {
+		t.Fatal("сообщение отправлено повторно без токена")
+	}
+
+	w := ts.do("GET", "/dead-letters?limit=1&offset=1", nil, auth)
+	var letters []*database.DeadLetter
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &letters) != nil || len(letters) != 1 || letters[0].ID != 1 {
+		t.Errorf("GET /dead-letters: %d %s", w.Code, w.Body)
+	}
+	if w := ts.do("GET", "/dead-letters?limit=0", nil, auth); w.Code != http.StatusBadRequest {
+		t.Errorf("GET /dead-letters?limit=0: %d, ожидается 400", w.Code)
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/dead-letters/2/replay", http.StatusOK},
+		{"/dead-letters/3/replay", http.StatusNotFound},
+		{"/dead-letters/x/replay", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := ts.do("POST", tt.target, nil, auth); w.Code != tt.want {
+			t.Errorf("POST %s: %d, ожидается %d", tt.target, w.Code, tt.want)
+		}
+	}
+	if len(ts.dlq.replayed) != 1 || ts.dlq.replayed[0] != "orders" {
+		t.Errorf("сообщения отправлены в каналы %v, ожидается [orders]", ts.dlq.replayed)
+	}
+}
+
+func TestAdminResync(t *testing.T) {
+	ts := newTestServer(t, config.UpsertReplace)
+	auth := http.Header{"Authorization": {"Bearer " + testAdminToken}}
+	tests := []struct {
+		name   string
+		resync error
+		header http.Header
+		want   int
+	}{
+		{"без токена", nil, nil, http.StatusUnauthorized},
+		{"загрузка запущена", nil, auth, http.StatusAccepted},
+		{"загрузка уже идет", cache.ErrWarmupRunning, auth, http.StatusConflict},
+		{"ошибка запуска", errors.New("нет соединения"), auth, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		ts.resync = tt.resync
+		if w := ts.do("POST", "/admin/cache/resync", nil, tt.header); w.Code != tt.want {
+			t.Errorf("%s: %d %s, ожидается %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"               // импорт пакета для работы с http протоколом
+	"wb_test/internal/cache" // импорт пакета для работы с кэшем
+
+	"github.com/gorilla/mux"               // импорт библиотеки gorilla/mux для маршрутизации http запросов
+	"go.opentelemetry.io/otel"             // импорт API OpenTelemetry
+	"go.opentelemetry.io/otel/attribute"   // импорт атрибутов spans
+	"go.opentelemetry.io/otel/codes"       // импорт статусов spans
+	"go.opentelemetry.io/otel/propagation" // импорт передачи контекста трассировки через заголовки
+	"go.opentelemetry.io/otel/trace"       // импорт API трассировки
+)
+
+// tracer создает spans обработки http запросов
+var tracer = otel.Tracer("wb_test/internal/http")
+
+// Tracing начинает серверный span на каждый запрос. Контекст трассировки клиента берется из заголовков traceparent и baggage,
+// span называется по методу и шаблону маршрута router, ответы 5xx отмечаются ошибкой.
+func Tracing(router *mux.Router) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(router, r)
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+			))
+			defer span.End()
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+			if rec.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+// lookupCache ищет готовый JSON заказа в кэше в отдельном span, чтобы в трассировке были видны промахи кэша
+func (s *Server) lookupCache(r *http.Request, orderUID string) (cache.Encoded, bool) {
+	_, span := tracer.Start(r.Context(), "cache lookup")
+	defer span.End()
+	encoded, found := s.Cache.GetEncoded(orderUID)
+	span.SetAttributes(attribute.Bool("cache.hit", found))
+	return encoded, found
+}
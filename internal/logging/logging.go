@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"context"                 // импорт пакета для передачи идентификаторов в контексте
+	"crypto/rand"             // импорт пакета для генерации идентификаторов запросов
+	"encoding/hex"            // импорт пакета для шестнадцатеричного кодирования
+	"fmt"                     // импорт пакета для форматированного вывода
+	"io"                      // импорт пакета с интерфейсом вывода
+	"log"                     // импорт пакета стандартного логгера
+	"log/slog"                // импорт пакета структурированного логирования
+	"strings"                 // импорт пакета для работы со строками
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+
+	"go.opentelemetry.io/otel/trace" // импорт API трассировки для trace_id в логах
+)
+
+// level текущий уровень логирования; меняется без перезапуска через SetLevel
+var level = new(slog.LevelVar)
+
+// Setup настраивает логгер по умолчанию: JSON или текст в w с уровнем из cfg.
+// Вывод стандартного пакета log тоже проходит через этот логгер с уровнем INFO.
+func Setup(cfg config.LogConfig, w io.Writer) error {
+	if err := SetLevel(cfg.Level); err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if cfg.Format == config.LogText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	log.SetFlags(0) // время и уровень добавляет slog
+	return nil
+}
+
+// Level возвращает текущий уровень логирования
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel меняет уровень логирования: debug, info, warn или error
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(name))); err != nil {
+		return fmt.Errorf("Некорректный уровень логирования %q: %v", name, err)
+	}
+	level.Set(l)
+	return nil
+}
+
+// ctxKey ключ значений логирования в контексте
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota // идентификатор http запроса
+	messageKey                 // канал и номер сообщения nats-streaming
+)
+
+// message канал и номер обрабатываемого сообщения
+type message struct {
+	subject  string
+	sequence uint64
+}
+
+// WithRequestID возвращает контекст с идентификатором запроса, который добавляется ко всем записям лога
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// NewRequestID генерирует случайный идентификатор запроса
+func NewRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithMessage возвращает контекст с каналом и номером сообщения nats-streaming, которые добавляются ко всем записям лога
+func WithMessage(ctx context.Context, subject string, sequence uint64) context.Context {
+	return context.WithValue(ctx, messageKey, message{subject: subject, sequence: sequence})
+}
+
+// contextHandler добавляет к записям лога идентификаторы из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if m, ok := ctx.Value(messageKey).(message); ok {
+		r.AddAttrs(slog.String("subject", m.subject), slog.Uint64("sequence", m.sequence))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() { // связываем запись лога с трассировкой
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
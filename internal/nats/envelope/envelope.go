@@ -0,0 +1,33 @@
+package envelope
+
+import (
+	"context"       // импорт пакета для передачи контекста трассировки
+	"encoding/json" // импорт пакета для работы с json
+
+	"go.opentelemetry.io/otel"             // импорт API OpenTelemetry
+	"go.opentelemetry.io/otel/propagation" // импорт форматов передачи контекста трассировки
+)
+
+// Envelope сообщение с заказом и заголовками. nats-streaming не поддерживает заголовки,
+// поэтому контекст трассировки (traceparent, tracestate) передается рядом с заказом.
+type Envelope struct {
+	Headers map[string]string `json:"headers,omitempty"` // заголовки в формате W3C Trace Context
+	Order   json.RawMessage   `json:"order"`             // заказ в JSON
+}
+
+// Wrap упаковывает заказ в конверт вместе с контекстом трассировки из ctx
+func Wrap(ctx context.Context, order []byte) ([]byte, error) {
+	headers := make(map[string]string)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+	return json.Marshal(Envelope{Headers: headers, Order: order})
+}
+
+// Unwrap извлекает заказ и контекст трассировки из сообщения.
+// Сообщения без конверта, например от старых издателей, возвращаются как есть с исходным ctx.
+func Unwrap(ctx context.Context, data []byte) (context.Context, []byte) {
+	var e Envelope
+	if err := json.Unmarshal(data, &e); err != nil || len(e.Order) == 0 {
+		return ctx, data // не конверт: заказ без поля order или некорректный JSON, который отклонит декодирование
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(e.Headers)), e.Order
+}
@@ -1,14 +1,44 @@
 package main
 
 import (
-	"github.com/nats-io/stan.go"
+	"context"
+	"flag"
 	"log"
+	"os"
+	"wb_test/internal/config"
+	"wb_test/internal/nats/envelope"
+	"wb_test/internal/tracing"
+
+	"github.com/nats-io/stan.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
-	nc, err := stan.Connect("test-cluster", "publisher-client", stan.NatsURL("nats://localhost:4222"))
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	clientID := fs.String("publisher-client-id", "publisher-client", "идентификатор клиента издателя в nats-streaming")
+	serviceName := fs.String("publisher-service-name", "order-publisher", "имя издателя в трассах")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Не удалось загрузить конфигурацию: %v", err)
+	}
+
+	cfg.Tracing.ServiceName = *serviceName
+	shutdown, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Не удалось настроить трассировку: %v", err)
+	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil { // отправляем span публикации до выхода
+			log.Printf("Не удалось отправить трассы: %v", err)
+		}
+	}()
+
+	nc, err := stan.Connect(cfg.NATS.ClusterID, *clientID, stan.NatsURL(cfg.NATS.URL))
 	if err != nil {
-		log.Fatal("Не удалось подключиться к nats: %v", err)
+		log.Fatalf("Не удалось подключиться к nats: %v", err)
 	}
 	defer nc.Close()
 
@@ -62,10 +92,30 @@ func main() {
 		"oof_shard": "1"
 	}`
 
-	err = nc.Publish("channel-name", []byte(message))
-	if err != nil {
-		log.Fatalf("Не удалось отправить сообщение: %v", err)
+	if err := publish(nc, cfg.NATS.Channel, []byte(message)); err != nil {
+		log.Printf("Не удалось отправить сообщение: %v", err)
+		return // log.Fatalf не дал бы отправить трассы
 	}
 
 	log.Println("Сообщение успешно отправлено")
 }
+
+// publish отправляет заказ в канал в конверте с контекстом трассировки, чтобы обработка в сервисе попала в ту же трассу
+func publish(nc stan.Conn, channel string, order []byte) error {
+	ctx, span := otel.Tracer("wb_test/internal/nats/publisher").Start(context.Background(), "publish "+channel,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "nats-streaming"),
+			attribute.String("messaging.destination.name", channel),
+		))
+	defer span.End()
+
+	data, err := envelope.Wrap(ctx, order)
+	if err == nil {
+		err = nc.Publish(channel, data)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package nats
+
+import (
+	"database/sql"              // импорт стандартного пакета для работы с базой данных
+	"encoding/json"             // импорт пакета для работы с json
+	"fmt"                       // импорт пакета для форматированного вывода
+	"log/slog"                  // импорт пакета структурированного логирования
+	"time"                      // импорт пакета для работы со временем
+	"wb_test/internal/database" // импорт локального пакета для работы с базой данных
+
+	"github.com/nats-io/stan.go"
+)
+
+// MaxRedeliveries количество повторных доставок, после которого заказ, не сохраненный в БД, отправляется в dead-letter
+const MaxRedeliveries = 5
+
+// DeadLetters сообщения, которые не удалось обработать: обработчик сообщений откладывает их,
+// а http обработчики просматривают и отправляют повторно
+type DeadLetters interface {
+	Send(msg *stan.Msg, stage string, cause error) error           // откладывает сообщение
+	List(limit, offset int) ([]*database.DeadLetter, error)        // возвращает сообщения, начиная с самых новых
+	Replay(id int64, channel string) (*database.DeadLetter, error) // отправляет сообщение повторно; nil, если его нет
+}
+
+var _ DeadLetters = (*DeadLetterQueue)(nil)
+
+// DeadLetterQueue отправляет необработанные сообщения в dead-letter канал и таблицу dead_letters
+type DeadLetterQueue struct {
+	nc      stan.Conn // соединение с nats-streaming
+	db      *sql.DB   // база данных для таблицы dead_letters
+	channel string    // dead-letter канал
+}
+
+// NewDeadLetterQueue создает очередь для сообщений, которые не удалось обработать
+func NewDeadLetterQueue(nc stan.Conn, db *sql.DB, channel string) *DeadLetterQueue {
+	return &DeadLetterQueue{nc: nc, db: db, channel: channel}
+}
+
+// Send публикует сообщение в dead-letter канал и сохраняет его в БД.
+// Ошибка возвращается, только если сообщение не удалось сохранить ни одним из способов.
+func (q *DeadLetterQueue) Send(msg *stan.Msg, stage string, cause error) error {
+	dl := &database.DeadLetter{
+		Subject:   msg.Subject,
+		Sequence:  msg.Sequence,
+		Stage:     stage,
+		Error:     cause.Error(),
+		Payload:   string(msg.Data),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	dbErr := database.SaveDeadLetter(q.db, dl) // сохраняем сообщение в таблицу dead_letters
+	if dbErr != nil {
+		slog.Error("Не удалось сохранить сообщение в dead_letters", "sequence", msg.Sequence, "error", dbErr)
+	}
+
+	data, err := json.Marshal(dl) // оборачиваем исходное сообщение вместе с причиной ошибки
+	if err != nil {
+		return fmt.Errorf("Ошибка кодирования dead letter: %v", err)
+	}
+	pubErr := q.nc.Publish(q.channel, data) // публикуем сообщение в dead-letter канал
+	if pubErr != nil {
+		slog.Error("Не удалось отправить сообщение в dead-letter канал", "sequence", msg.Sequence, "channel", q.channel, "error", pubErr)
+	}
+
+	if dbErr != nil && pubErr != nil {
+		return fmt.Errorf("Ошибка отправки в dead-letter: %v; %v", dbErr, pubErr)
+	}
+	slog.Warn("Сообщение отправлено в dead-letter", "sequence", msg.Sequence, "stage", stage, "error", cause)
+	return nil
+}
+
+// List возвращает сообщения из dead_letters, начиная с самых новых
+func (q *DeadLetterQueue) List(limit, offset int) ([]*database.DeadLetter, error) {
+	return database.GetDeadLetters(q.db, limit, offset)
+}
+
+// Replay повторно публикует сообщение из dead_letters в канал заказов
+func (q *DeadLetterQueue) Replay(id int64, channel string) (*database.DeadLetter, error) {
+	dl, err := database.GetDeadLetter(q.db, id) // получаем сообщение из БД
+	if err != nil || dl == nil {
+		return nil, err
+	}
+
+	err = q.nc.Publish(channel, []byte(dl.Payload)) // отправляем исходное сообщение в обычный канал обработки
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка повторной отправки сообщения: %v", err)
+	}
+
+	err = database.MarkDeadLetterReplayed(q.db, id) // отмечаем сообщение как отправленное повторно
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	dl.ReplayedAt = &now
+	return dl, nil
+}
@@ -0,0 +1,54 @@
+package nats
+
+import (
+	"context"  // импорт пакета для ограничения времени ожидания
+	"log/slog" // импорт пакета структурированного логирования
+	"sync"     // импорт пакета для синхронизации goroutine
+
+	"github.com/nats-io/stan.go"
+)
+
+// Drainer отслеживает сообщения, обработка которых еще не завершилась, чтобы при остановке дождаться их.
+// После начала остановки новые сообщения не обрабатываются и не подтверждаются,
+// nats-streaming доставит их повторно после AckWait или перезапуска. Нулевое значение готово к использованию.
+type Drainer struct {
+	mu       sync.RWMutex // защищает draining и не дает начать обработку после начала ожидания
+	draining bool
+	inflight sync.WaitGroup
+}
+
+// Wrap возвращает обработчик, который учитывает сообщения, обрабатываемые handler
+func (d *Drainer) Wrap(handler func(*stan.Msg)) func(*stan.Msg) {
+	return func(msg *stan.Msg) {
+		d.mu.RLock()
+		if d.draining {
+			d.mu.RUnlock()
+			slog.Info("Сообщение получено во время остановки, оставляем неподтвержденным", "subject", msg.Subject, "sequence", msg.Sequence)
+			return
+		}
+		d.inflight.Add(1)
+		d.mu.RUnlock()
+		defer d.inflight.Done()
+
+		handler(msg)
+	}
+}
+
+// Drain прекращает обработку новых сообщений и ждет завершения начатых, пока не истечет ctx
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,35 @@
+package nats
+
+import (
+	"github.com/prometheus/client_golang/prometheus"          // импорт библиотеки метрик prometheus
+	"github.com/prometheus/client_golang/prometheus/promauto" // импорт пакета для регистрации метрик при объявлении
+)
+
+var (
+	// messagesReceived количество сообщений, полученных из канала заказов
+	messagesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_nats_messages_received_total",
+		Help: "Количество сообщений, полученных из канала заказов.",
+	})
+	// messagesRedelivered количество повторно доставленных сообщений
+	messagesRedelivered = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_nats_messages_redelivered_total",
+		Help: "Количество сообщений, доставленных повторно.",
+	})
+	// messagesAcked количество подтвержденных сообщений
+	messagesAcked = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "orders_nats_messages_acked_total",
+		Help: "Количество подтвержденных сообщений.",
+	})
+	// messagesFailed количество сообщений, которые не удалось обработать, по этапу обработки
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_nats_messages_failed_total",
+		Help: "Количество сообщений, которые не удалось обработать, по этапу: decode, validate, save.",
+	}, []string{"stage"})
+	// processingDuration длительность обработки сообщения по результату
+	processingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "orders_nats_message_processing_seconds",
+		Help:    "Длительность обработки сообщения по результату: saved, duplicate, skipped, dead_letter, failed.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+)
@@ -0,0 +1,99 @@
+package nats
+
+import (
+	"errors" // импорт пакета для работы с ошибками
+	"fmt"    // импорт пакета для форматированного вывода
+	"sync"   // импорт пакета для синхронизации goroutine
+	"time"   // импорт пакета для работы со временем
+
+	"github.com/nats-io/stan.go"
+)
+
+// IngestStatus состояние приема заказов из nats-streaming
+type IngestStatus struct {
+	LastIngestAt   *time.Time `json:"last_ingest_at,omitempty"`  // время последнего сохраненного заказа
+	LastSequence   uint64     `json:"last_sequence"`             // номер последнего сообщения, заказ из которого сохранен
+	LastError      string     `json:"last_error,omitempty"`      // последняя ошибка обработки сообщения
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`   // время последней ошибки обработки
+	ConnectionLost string     `json:"connection_lost,omitempty"` // причина потери соединения с nats-streaming
+}
+
+// ingest хранит время последнего сохранения и последние ошибки
+var ingest struct {
+	mu             sync.Mutex
+	lastIngestAt   time.Time
+	lastError      string
+	lastErrorAt    time.Time
+	connectionLost error
+}
+
+// recordIngest отмечает успешное сохранение заказа из сообщения
+func recordIngest(sequence uint64) {
+	lastSequence.Store(sequence)
+	ingest.mu.Lock()
+	ingest.lastIngestAt = time.Now()
+	ingest.mu.Unlock()
+}
+
+// recordError запоминает ошибку обработки сообщения
+func recordError(err error) {
+	ingest.mu.Lock()
+	ingest.lastError = err.Error()
+	ingest.lastErrorAt = time.Now()
+	ingest.mu.Unlock()
+}
+
+// recordConnectionLost запоминает причину потери соединения с nats-streaming
+func recordConnectionLost(reason error) {
+	if reason == nil {
+		reason = errors.New("соединение закрыто сервером")
+	}
+	ingest.mu.Lock()
+	ingest.connectionLost = reason
+	ingest.mu.Unlock()
+}
+
+// Status возвращает состояние приема заказов
+func Status() IngestStatus {
+	ingest.mu.Lock()
+	defer ingest.mu.Unlock()
+	s := IngestStatus{LastSequence: lastSequence.Load(), LastError: ingest.lastError}
+	if !ingest.lastIngestAt.IsZero() {
+		at := ingest.lastIngestAt
+		s.LastIngestAt = &at
+	}
+	if !ingest.lastErrorAt.IsZero() {
+		at := ingest.lastErrorAt
+		s.LastErrorAt = &at
+	}
+	if ingest.connectionLost != nil {
+		s.ConnectionLost = ingest.connectionLost.Error()
+	}
+	return s
+}
+
+// Alive возвращает ошибку, если сессия nats-streaming потеряна.
+// stan.go не восстанавливает сессию сам, поэтому процесс, который больше не получает заказы, нужно перезапустить:
+// durable подписка продолжит чтение с первого неподтвержденного сообщения.
+func Alive() error {
+	ingest.mu.Lock()
+	defer ingest.mu.Unlock()
+	if ingest.connectionLost != nil {
+		return fmt.Errorf("Соединение с nats-streaming потеряно: %v", ingest.connectionLost)
+	}
+	return nil
+}
+
+// Check проверяет, что соединение с nats-streaming установлено и подписка активна
+func Check(nc stan.Conn, sub stan.Subscription) error {
+	if err := Alive(); err != nil {
+		return err
+	}
+	if conn := nc.NatsConn(); conn == nil || !conn.IsConnected() {
+		return errors.New("Нет соединения с nats")
+	}
+	if !sub.IsValid() {
+		return errors.New("Подписка на канал неактивна")
+	}
+	return nil
+}
@@ -1,21 +1,157 @@
 package nats
 
 import (
+	"context"                   // импорт пакета для передачи номера сообщения в логи и запросы к БД
+	"crypto/sha256"             // импорт пакета для вычисления хэша
+	"encoding/hex"              // импорт пакета для шестнадцатеричного кодирования
+	"encoding/json"             // импорт пакета для работы с json
+	"errors"                    // импорт пакета для работы с ошибками
+	"expvar"                    // импорт пакета для публикации счетчиков
+	"fmt"                       // импорт пакета для форматированного вывода
+	"log/slog"                  // импорт пакета структурированного логирования
+	"sync/atomic"               // импорт пакета для атомарных счетчиков
+	"time"                      // импорт пакета для работы со временем
+	"wb_test/internal/cache"    // импорт пакета для работы с кэшем
+	"wb_test/internal/config"   // импорт пакета с настройками сервиса
+	"wb_test/internal/database" // импорт локального пакета для работы с базой данных
+	"wb_test/internal/logging"  // импорт пакета для добавления номера сообщения в логи
+
 	"github.com/nats-io/stan.go"
 )
 
-func ConnectNATS(clusterID, clientID, url string) (stan.Conn, error) {
-	nc, err := stan.Connect(clusterID, clientID, stan.NatsURL(url))
+// duplicatesSuppressed счетчик повторно доставленных сообщений, которые были подтверждены без сохранения
+var duplicatesSuppressed = expvar.NewInt("ingest_duplicates_suppressed")
+
+// lastSequence номер последнего сообщения, заказ из которого сохранен в БД
+var lastSequence atomic.Uint64
+
+// AckWait время, в течение которого nats-streaming ждет подтверждения сообщения перед повторной доставкой
+const AckWait = 30 * time.Second
+
+func ConnectNATS(cfg config.NATSConfig) (stan.Conn, error) {
+	nc, err := stan.Connect(cfg.ClusterID, cfg.ClientID, stan.NatsURL(cfg.URL),
+		stan.SetConnectionLostHandler(func(_ stan.Conn, reason error) { // nats-streaming не восстанавливает сессию сам, отражаем это в /healthz и /readyz
+			slog.Error("Соединение с nats-streaming потеряно", "error", reason)
+			recordConnectionLost(reason)
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
 	return nc, nil
 }
 
-func Subscribe(nc stan.Conn, channelName, queueName string, handler func(*stan.Msg)) (stan.Subscription, error) {
-	sub, err := nc.QueueSubscribe(channelName, queueName, handler, stan.DurableName("my-durable"))
+// Subscribe создает durable queue подписку с ручным подтверждением сообщений
+func Subscribe(nc stan.Conn, cfg config.NATSConfig, handler func(*stan.Msg)) (stan.Subscription, error) {
+	sub, err := nc.QueueSubscribe(cfg.Channel, cfg.Queue, handler,
+		stan.DurableName(cfg.DurableName), // durable подписка продолжает чтение с места остановки после перезапуска
+		stan.SetManualAckMode(),           // подтверждаем сообщение только после сохранения заказа
+		stan.AckWait(AckWait),             // время ожидания подтверждения до повторной доставки
+		stan.DeliverAllAvailable(),        // при первом подключении читаем канал с начала
+	)
 	if err != nil {
 		return nil, err
 	}
 	return sub, nil
 }
+
+// OrderHandler возвращает обработчик сообщений, который сохраняет заказ в хранилище и кэш.
+// Политику повторного сохранения заказа применяет хранилище.
+// Сообщения, которые не удалось обработать, отправляются в dead-letter очередь.
+func OrderHandler(orders database.IngestRepository, orderCache cache.OrderCache, dlq DeadLetters) func(*stan.Msg) {
+	return orderHandler(orders, orderCache, dlq, ack)
+}
+
+// orderHandler обработчик сообщений с функцией подтверждения: stan.Msg.Ack работает только
+// для сообщений из подписки, поэтому тесты передают свою функцию
+func orderHandler(orders database.IngestRepository, orderCache cache.OrderCache, dlq DeadLetters, ack func(*stan.Msg)) func(*stan.Msg) {
+	return func(msg *stan.Msg) {
+		ctx := logging.WithMessage(context.Background(), msg.Subject, msg.Sequence) // канал и номер сообщения попадают во все записи лога
+		start := time.Now()
+		ctx, span, data := startProcessing(ctx, msg) // заказ из конверта и контекст трассировки издателя
+		result := "failed"                           // результат обработки для метрики длительности и span
+		defer func() {
+			processingDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+			endProcessing(span, result)
+		}()
+		messagesReceived.Inc()
+		if msg.Redelivered {
+			messagesRedelivered.Inc()
+		}
+
+		var order database.Order
+		err := json.Unmarshal(data, &order) // декодируем JSON заказа в структуру
+		if err != nil {
+			slog.WarnContext(ctx, "Ошибка декодирования сообщения", "error", err) // логируем ошибку декодирования
+			messagesFailed.WithLabelValues(database.StageDecode).Inc()
+			deadLetter(dlq, msg, database.StageDecode, err, ack) // повторная доставка не исправит некорректный JSON
+			result = "dead_letter"
+			return
+		}
+
+		if errs := validate(ctx, &order); len(errs) > 0 {
+			slog.WarnContext(ctx, "Заказ не прошел проверку", "order_uid", order.OrderUID, "errors", errs) // логируем ошибки проверки
+			messagesFailed.WithLabelValues(database.StageValidate).Inc()
+			deadLetter(dlq, msg, database.StageValidate, fmt.Errorf("%v", errs), ack) // некорректный заказ не станет корректным при повторной доставке
+			result = "dead_letter"
+			return
+		}
+
+		key := database.IngestedMessage{Subject: msg.Subject, Sequence: msg.Sequence, ContentHash: contentHash(data)} // дубликат определяется по номеру сообщения и хэшу заказа без конверта
+		err = orders.SaveIngested(ctx, &order, key)                                                                   // сохраняем заказ вместе с отметкой о сообщении
+		if errors.Is(err, database.ErrDuplicateMessage) {
+			duplicatesSuppressed.Add(1)
+			slog.InfoContext(ctx, "Сообщение уже обработано, пропускаем", "order_uid", order.OrderUID) // повторная доставка не меняет данные
+			ack(msg)
+			result = "duplicate"
+			return
+		}
+		if errors.Is(err, database.ErrOrderExists) || errors.Is(err, database.ErrOrderNotNewer) {
+			slog.InfoContext(ctx, "Заказ пропущен политикой сохранения", "order_uid", order.OrderUID, "reason", err) // политика сохранения оставила прежний заказ
+			ack(msg)
+			result = "skipped"
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка сохранения заказа", "order_uid", order.OrderUID, "redelivery_count", msg.RedeliveryCount, "error", err) // логируем ошибку сохранения
+			recordError(err)
+			messagesFailed.WithLabelValues(database.StageSave).Inc()
+			if msg.RedeliveryCount >= MaxRedeliveries {
+				deadLetter(dlq, msg, database.StageSave, err, ack) // исчерпали повторные доставки, откладываем сообщение
+				result = "dead_letter"
+			}
+			return // не подтверждаем сообщение, nats-streaming доставит его повторно
+		}
+
+		orderCache.Set(&order)     // сохраняем заказ в кэш
+		recordIngest(msg.Sequence) // запоминаем номер и время сообщения для /status
+		ack(msg)                   // подтверждаем сообщение после успешного сохранения
+		result = "saved"
+		slog.InfoContext(ctx, "Заказ сохранен", "order_uid", order.OrderUID)
+	}
+}
+
+// deadLetter отправляет сообщение в dead-letter очередь и подтверждает его.
+// Если сообщение не удалось отложить, оно остается неподтвержденным и будет доставлено повторно.
+func deadLetter(dlq DeadLetters, msg *stan.Msg, stage string, cause error, ack func(*stan.Msg)) {
+	if err := dlq.Send(msg, stage, cause); err != nil {
+		slog.Error("Сообщение не отправлено в dead-letter", "sequence", msg.Sequence, "error", err)
+		return
+	}
+	ack(msg)
+}
+
+// contentHash возвращает sha256 содержимого сообщения в шестнадцатеричном виде
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ack подтверждает получение сообщения и логирует ошибку подтверждения
+func ack(msg *stan.Msg) {
+	if err := msg.Ack(); err != nil {
+		slog.Error("Ошибка подтверждения сообщения", "sequence", msg.Sequence, "error", err)
+		return
+	}
+	messagesAcked.Inc()
+}
@@ -0,0 +1,124 @@
+package nats
+
+import (
+	"context"                    // импорт пакета для вызова методов хранилища
+	"encoding/json"              // импорт пакета для работы с json
+	"errors"                     // импорт пакета для работы с ошибками
+	"slices"                     // импорт пакета для сравнения срезов
+	"testing"                    // импорт пакета для тестов
+	"wb_test/internal/cache"     // импорт пакета для работы с кэшем
+	"wb_test/internal/config"    // импорт пакета с настройками сервиса
+	"wb_test/internal/database"  // импорт локального пакета для работы с базой данных
+	"wb_test/internal/ordertest" // импорт пакета с заказом для тестов
+
+	"github.com/nats-io/stan.go"
+	"github.com/nats-io/stan.go/pb"
+)
+
+// fakeDeadLetters dead-letter очередь в памяти
+type fakeDeadLetters struct {
+	sent    []string // этапы, на которых сообщения отправлены в dead-letter
+	sendErr error    // ошибка, которую возвращает Send
+}
+
+func (q *fakeDeadLetters) Send(_ *stan.Msg, stage string, _ error) error {
+	if q.sendErr != nil {
+		return q.sendErr
+	}
+	q.sent = append(q.sent, stage)
+	return nil
+}
+
+func (q *fakeDeadLetters) List(int, int) ([]*database.DeadLetter, error)      { return nil, nil }
+func (q *fakeDeadLetters) Replay(int64, string) (*database.DeadLetter, error) { return nil, nil }
+
+// failingRepository хранилище, которое не может сохранить заказ
+type failingRepository struct{}
+
+func (failingRepository) SaveIngested(context.Context, *database.Order, database.IngestedMessage) error {
+	return errors.New("нет соединения с базой данных")
+}
+
+// orderMessage возвращает сообщение с заказом в JSON
+func orderMessage(t *testing.T, sequence uint64, order *database.Order) *stan.Msg {
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &stan.Msg{MsgProto: pb.MsgProto{Subject: "orders", Sequence: sequence, Data: data}}
+}
+
+func TestOrderHandler(t *testing.T) {
+	invalid := ordertest.ValidOrder("invalid")
+	invalid.Delivery.Email = "not an email"
+	changed := ordertest.ValidOrder("a")
+	changed.TrackNumber, changed.Items[0].TrackNumber = "CHANGED", "CHANGED"
+
+	tests := []struct {
+		name       string
+		repo       database.IngestRepository // nil - хранилище в памяти с заказом a из сообщения 1
+		sendErr    error
+		msg        func(t *testing.T) *stan.Msg
+		wantAck    bool
+		wantDLQ    []string
+		wantCached string // номер трека заказа a в кэше, пусто - заказа в кэше нет
+	}{
+		{"новый заказ", nil, nil, func(t *testing.T) *stan.Msg { return orderMessage(t, 2, ordertest.ValidOrder("a")) },
+			true, nil, "WBILMTESTTRACK"},
+		{"повторная доставка", nil, nil, func(t *testing.T) *stan.Msg { return orderMessage(t, 1, ordertest.ValidOrder("a")) },
+			true, nil, ""},
+		{"номер повторился с другим содержимым", nil, nil, func(t *testing.T) *stan.Msg { return orderMessage(t, 1, changed) },
+			true, nil, "CHANGED"},
+		{"некорректный JSON", nil, nil, func(t *testing.T) *stan.Msg {
+			return &stan.Msg{MsgProto: pb.MsgProto{Subject: "orders", Sequence: 2, Data: []byte("{")}}
+		}, true, []string{database.StageDecode}, ""},
+		{"заказ не прошел проверку", nil, nil, func(t *testing.T) *stan.Msg { return orderMessage(t, 2, invalid) },
+			true, []string{database.StageValidate}, ""},
+		{"dead-letter недоступна", nil, errors.New("нет соединения"), func(t *testing.T) *stan.Msg { return orderMessage(t, 2, invalid) },
+			false, nil, ""},
+		{"ошибка сохранения", failingRepository{}, nil, func(t *testing.T) *stan.Msg { return orderMessage(t, 2, ordertest.ValidOrder("a")) },
+			false, nil, ""},
+		{"ошибка сохранения после всех повторных доставок", failingRepository{}, nil, func(t *testing.T) *stan.Msg {
+			msg := orderMessage(t, 2, ordertest.ValidOrder("a"))
+			msg.Redelivered, msg.RedeliveryCount = true, MaxRedeliveries
+			return msg
+		}, true, []string{database.StageSave}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := tt.repo
+			if repo == nil {
+				memory := database.NewMemoryRepository(config.UpsertReplace)
+				first := orderMessage(t, 1, ordertest.ValidOrder("a"))
+				err := memory.SaveIngested(context.Background(), ordertest.ValidOrder("a"),
+					database.IngestedMessage{Subject: first.Subject, Sequence: first.Sequence, ContentHash: contentHash(first.Data)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				repo = memory
+			}
+			c := cache.New(config.CacheConfig{})
+			defer c.Close()
+			dlq := &fakeDeadLetters{sendErr: tt.sendErr}
+			var acked []uint64
+			handler := orderHandler(repo, c, dlq, func(msg *stan.Msg) { acked = append(acked, msg.Sequence) })
+
+			msg := tt.msg(t)
+			handler(msg)
+
+			if gotAck := len(acked) == 1 && acked[0] == msg.Sequence; gotAck != tt.wantAck || len(acked) > 1 {
+				t.Errorf("подтверждено %v, ожидается подтверждение %v", acked, tt.wantAck)
+			}
+			if !slices.Equal(dlq.sent, tt.wantDLQ) {
+				t.Errorf("в dead-letter %v, ожидается %v", dlq.sent, tt.wantDLQ)
+			}
+			cached := ""
+			if order, ok := c.Get("a"); ok {
+				cached = order.TrackNumber
+			}
+			if cached != tt.wantCached {
+				t.Errorf("в кэше заказ с треком %q, ожидается %q", cached, tt.wantCached)
+			}
+		})
+	}
+}
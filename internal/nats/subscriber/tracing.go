@@ -0,0 +1,55 @@
+package nats
+
+import (
+	"context"                        // импорт пакета с контекстом трассировки
+	"strconv"                        // импорт пакета для преобразования чисел в строки
+	"time"                           // импорт пакета для работы со временем
+	"wb_test/internal/database"      // импорт локального пакета для работы с базой данных
+	"wb_test/internal/nats/envelope" // импорт пакета конверта сообщения
+	"wb_test/internal/validation"    // импорт пакета для проверки заказов
+
+	"github.com/nats-io/stan.go"
+	"go.opentelemetry.io/otel"           // импорт API OpenTelemetry
+	"go.opentelemetry.io/otel/attribute" // импорт атрибутов spans
+	"go.opentelemetry.io/otel/codes"     // импорт статусов spans
+	"go.opentelemetry.io/otel/trace"     // импорт API трассировки
+)
+
+// tracer создает spans обработки сообщений
+var tracer = otel.Tracer("wb_test/internal/nats/subscriber")
+
+// startProcessing извлекает заказ и контекст трассировки из конверта и начинает span обработки сообщения.
+// Если сообщение пришло с контекстом издателя, рядом добавляется span ожидания в канале от публикации до получения.
+func startProcessing(ctx context.Context, msg *stan.Msg) (context.Context, trace.Span, []byte) {
+	ctx, data := envelope.Unwrap(ctx, msg.Data)
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "nats-streaming"),
+		attribute.String("messaging.destination.name", msg.Subject),
+		attribute.String("messaging.message.id", strconv.FormatUint(msg.Sequence, 10)),
+		attribute.Int("messaging.stan.redelivery_count", int(msg.RedeliveryCount)),
+	}
+	if trace.SpanContextFromContext(ctx).IsValid() && msg.Timestamp > 0 {
+		_, wait := tracer.Start(ctx, "wait "+msg.Subject, trace.WithTimestamp(time.Unix(0, msg.Timestamp)), trace.WithAttributes(attrs...))
+		wait.End()
+	}
+	ctx, span := tracer.Start(ctx, "process "+msg.Subject, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrs...))
+	return ctx, span, data
+}
+
+// endProcessing завершает span обработки с результатом; сообщения, которые не удалось сохранить или пришлось отложить, отмечаются ошибкой
+func endProcessing(span trace.Span, result string) {
+	span.SetAttributes(attribute.String("messaging.result", result))
+	if result == "failed" || result == "dead_letter" {
+		span.SetStatus(codes.Error, result)
+	}
+	span.End()
+}
+
+// validate проверяет заказ в отдельном span
+func validate(ctx context.Context, order *database.Order) []validation.FieldError {
+	_, span := tracer.Start(ctx, "validate")
+	defer span.End()
+	errs := validation.Validate(order)
+	span.SetAttributes(attribute.Int("validation.errors", len(errs)))
+	return errs
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"                 // импорт пакета для передачи контекста трассировки
+	"fmt"                     // импорт пакета для форматированного вывода
+	"os"                      // импорт пакета для вывода spans в stdout
+	"wb_test/internal/config" // импорт пакета с настройками сервиса
+
+	"go.opentelemetry.io/otel"                                        // импорт API OpenTelemetry
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp" // импорт экспорта spans по OTLP/HTTP
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"           // импорт экспорта spans в stdout
+	"go.opentelemetry.io/otel/propagation"                            // импорт форматов передачи контекста трассировки
+	"go.opentelemetry.io/otel/sdk/resource"                           // импорт описания сервиса в трассах
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"                     // импорт SDK трассировки
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"                // импорт стандартных имен атрибутов
+)
+
+// Setup настраивает глобальный TracerProvider и передачу контекста в формате W3C Trace Context.
+// Возвращает функцию, которая отправляет оставшиеся spans и останавливает экспорт.
+// При exporter=none spans не создаются, но контекст из входящих запросов и сообщений передается дальше.
+func Setup(ctx context.Context, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case config.TraceNone:
+		return func(context.Context) error { return nil }, nil
+	case config.TraceStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case config.TraceOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("Неизвестный способ экспорта трасс: %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка создания экспорта трасс: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка описания сервиса для трасс: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))), // решение вызывающего сервиса сохраняется
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
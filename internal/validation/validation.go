@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"fmt"                       // импорт пакета для форматированного вывода
+	"net/mail"                  // импорт пакета для разбора email адресов
+	"regexp"                    // импорт пакета для регулярных выражений
+	"time"                      // импорт пакета для работы со временем
+	"wb_test/internal/database" // импорт локального пакета для работы с базой данных
+)
+
+// FieldError описывает причину, по которой поле заказа не прошло проверку
+type FieldError struct {
+	Field   string `json:"field"`   // путь к полю в JSON заказа, например delivery.email или items[0].price
+	Message string `json:"message"` // описание ошибки
+}
+
+// Error реализует интерфейс error
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+var (
+	phoneRe    = regexp.MustCompile(`^\+?[0-9]{7,15}$`) // телефон в международном формате без разделителей
+	currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)       // код валюты по ISO 4217
+)
+
+// Validate проверяет обязательные поля, форматы и денежные инварианты заказа.
+// Возвращает nil, если заказ корректен.
+func Validate(order *database.Order) []FieldError {
+	v := &validator{}
+
+	v.required("order_uid", order.OrderUID)
+	v.required("track_number", order.TrackNumber)
+	v.required("entry", order.Entry)
+	v.required("locale", order.Locale)
+	v.required("delivery_service", order.DeliveryService)
+	v.required("date_created", order.DateCreated)
+	if order.DateCreated != "" {
+		if _, err := time.Parse(time.RFC3339, order.DateCreated); err != nil {
+			v.add("date_created", "ожидается дата в формате RFC3339")
+		}
+	}
+
+	// Проверяем данные о доставке
+	v.required("delivery.name", order.Delivery.Name)
+	v.required("delivery.phone", order.Delivery.Phone)
+	v.required("delivery.city", order.Delivery.City)
+	v.required("delivery.address", order.Delivery.Address)
+	v.required("delivery.email", order.Delivery.Email)
+	if order.Delivery.Phone != "" && !phoneRe.MatchString(order.Delivery.Phone) {
+		v.add("delivery.phone", "ожидается номер телефона из 7-15 цифр, допускается + в начале")
+	}
+	if order.Delivery.Email != "" {
+		if addr, err := mail.ParseAddress(order.Delivery.Email); err != nil || addr.Address != order.Delivery.Email {
+			v.add("delivery.email", "некорректный email")
+		}
+	}
+
+	// Проверяем данные об оплате
+	v.required("payment.transaction", order.Payment.Transaction)
+	v.required("payment.currency", order.Payment.Currency)
+	v.required("payment.provider", order.Payment.Provider)
+	if order.Payment.Currency != "" && !currencyRe.MatchString(order.Payment.Currency) {
+		v.add("payment.currency", "ожидается трехбуквенный код валюты ISO 4217")
+	}
+	v.nonNegative("payment.amount", order.Payment.Amount)
+	v.nonNegative("payment.delivery_cost", order.Payment.DeliveryCost)
+	v.nonNegative("payment.goods_total", order.Payment.GoodsTotal)
+	v.nonNegative("payment.custom_fee", order.Payment.CustomFee)
+	if order.Payment.PaymentDt <= 0 {
+		v.add("payment.payment_dt", "ожидается unix время оплаты")
+	}
+
+	// Проверяем товары
+	if len(order.Items) == 0 {
+		v.add("items", "заказ должен содержать хотя бы один товар")
+	}
+	goodsTotal := 0
+	for i, item := range order.Items {
+		prefix := fmt.Sprintf("items[%d]", i)
+		v.required(prefix+".name", item.Name)
+		v.required(prefix+".rid", item.Rid)
+		if item.TrackNumber != order.TrackNumber {
+			v.add(prefix+".track_number", "не совпадает с track_number заказа")
+		}
+		v.nonNegative(prefix+".price", item.Price)
+		v.nonNegative(prefix+".total_price", item.TotalPrice)
+		if item.Sale < 0 || item.Sale > 100 {
+			v.add(prefix+".sale", "скидка должна быть в диапазоне от 0 до 100")
+		}
+		goodsTotal += item.TotalPrice
+	}
+
+	// Проверяем денежные инварианты
+	if len(order.Items) > 0 && order.Payment.GoodsTotal != goodsTotal {
+		v.add("payment.goods_total", fmt.Sprintf("должно совпадать с суммой items[].total_price (%d)", goodsTotal))
+	}
+	expectedAmount := order.Payment.GoodsTotal + order.Payment.DeliveryCost + order.Payment.CustomFee
+	if order.Payment.Amount != expectedAmount {
+		v.add("payment.amount", fmt.Sprintf("должно равняться goods_total + delivery_cost + custom_fee (%d)", expectedAmount))
+	}
+
+	return v.errs
+}
+
+// validator накапливает ошибки проверки полей
+type validator struct {
+	errs []FieldError
+}
+
+// add добавляет ошибку для поля
+func (v *validator) add(field, message string) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: message})
+}
+
+// required добавляет ошибку, если строковое поле пустое
+func (v *validator) required(field, value string) {
+	if value == "" {
+		v.add(field, "обязательное поле")
+	}
+}
+
+// nonNegative добавляет ошибку, если денежное поле отрицательное
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.add(field, "значение не может быть отрицательным")
+	}
+}
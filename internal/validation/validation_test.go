@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"slices"                     // импорт пакета для сравнения срезов
+	"testing"                    // импорт пакета для тестов
+	"wb_test/internal/database"  // импорт локального пакета для работы с базой данных
+	"wb_test/internal/ordertest" // импорт пакета с заказом для тестов
+)
+
+func TestValidateAcceptsValidOrder(t *testing.T) {
+	if errs := Validate(ordertest.ValidOrder("b563feb7b2b84b6test")); errs != nil {
+		t.Fatalf("Validate() = %v, ожидается nil", errs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *database.Order)
+		fields []string // поля с ошибками в порядке проверки
+	}{
+		{"нет order_uid", func(o *database.Order) { o.OrderUID = "" }, []string{"order_uid"}},
+		{"нет track_number", func(o *database.Order) { o.TrackNumber = ""; o.Items[0].TrackNumber = "" }, []string{"track_number"}},
+		{"нет entry", func(o *database.Order) { o.Entry = "" }, []string{"entry"}},
+		{"нет locale", func(o *database.Order) { o.Locale = "" }, []string{"locale"}},
+		{"нет delivery_service", func(o *database.Order) { o.DeliveryService = "" }, []string{"delivery_service"}},
+		{"нет date_created", func(o *database.Order) { o.DateCreated = "" }, []string{"date_created"}},
+		{"date_created не RFC3339", func(o *database.Order) { o.DateCreated = "26.11.2021" }, []string{"date_created"}},
+		{"date_created с долями секунды", func(o *database.Order) { o.DateCreated = "2021-11-26T06:22:19.123+03:00" }, nil},
+		{"нет имени получателя", func(o *database.Order) { o.Delivery.Name = "" }, []string{"delivery.name"}},
+		{"нет телефона", func(o *database.Order) { o.Delivery.Phone = "" }, []string{"delivery.phone"}},
+		{"телефон с разделителями", func(o *database.Order) { o.Delivery.Phone = "+972 000-00-00" }, []string{"delivery.phone"}},
+		{"короткий телефон", func(o *database.Order) { o.Delivery.Phone = "123456" }, []string{"delivery.phone"}},
+		{"телефон без плюса", func(o *database.Order) { o.Delivery.Phone = "9720000000" }, nil},
+		{"нет города", func(o *database.Order) { o.Delivery.City = "" }, []string{"delivery.city"}},
+		{"нет адреса", func(o *database.Order) { o.Delivery.Address = "" }, []string{"delivery.address"}},
+		{"нет email", func(o *database.Order) { o.Delivery.Email = "" }, []string{"delivery.email"}},
+		{"email без домена", func(o *database.Order) { o.Delivery.Email = "test" }, []string{"delivery.email"}},
+		{"email с именем", func(o *database.Order) { o.Delivery.Email = "Test <test@gmail.com>" }, []string{"delivery.email"}},
+		{"нет transaction", func(o *database.Order) { o.Payment.Transaction = "" }, []string{"payment.transaction"}},
+		{"нет провайдера", func(o *database.Order) { o.Payment.Provider = "" }, []string{"payment.provider"}},
+		{"нет валюты", func(o *database.Order) { o.Payment.Currency = "" }, []string{"payment.currency"}},
+		{"валюта строчными", func(o *database.Order) { o.Payment.Currency = "usd" }, []string{"payment.currency"}},
+		{"нет времени оплаты", func(o *database.Order) { o.Payment.PaymentDt = 0 }, []string{"payment.payment_dt"}},
+		{"отрицательная пошлина", func(o *database.Order) { o.Payment.CustomFee = -10; o.Payment.Amount -= 10 }, []string{"payment.custom_fee"}},
+		{"отрицательная доставка", func(o *database.Order) { o.Payment.DeliveryCost = -1500; o.Payment.Amount = -1183 },
+			[]string{"payment.amount", "payment.delivery_cost"}},
+		{"нет товаров", func(o *database.Order) { o.Items = nil; o.Payment.GoodsTotal = 0; o.Payment.Amount = 1500 }, []string{"items"}},
+		{"нет имени товара", func(o *database.Order) { o.Items[0].Name = "" }, []string{"items[0].name"}},
+		{"нет rid товара", func(o *database.Order) { o.Items[0].Rid = "" }, []string{"items[0].rid"}},
+		{"чужой track_number товара", func(o *database.Order) { o.Items[0].TrackNumber = "OTHER" }, []string{"items[0].track_number"}},
+		{"отрицательная цена товара", func(o *database.Order) { o.Items[0].Price = -1 }, []string{"items[0].price"}},
+		{"скидка больше 100", func(o *database.Order) { o.Items[0].Sale = 101 }, []string{"items[0].sale"}},
+		{"отрицательная скидка", func(o *database.Order) { o.Items[0].Sale = -1 }, []string{"items[0].sale"}},
+		{"goods_total не равен сумме товаров", func(o *database.Order) { o.Payment.GoodsTotal = 300; o.Payment.Amount = 1800 },
+			[]string{"payment.goods_total"}},
+		{"amount не равен сумме частей", func(o *database.Order) { o.Payment.Amount = 1000 }, []string{"payment.amount"}},
+		{"второй товар учитывается в goods_total", func(o *database.Order) {
+			o.Items = append(o.Items, o.Items[0])
+			o.Payment.GoodsTotal, o.Payment.Amount = 634, 2134
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := ordertest.ValidOrder("b563feb7b2b84b6test")
+			tt.modify(order)
+			errs := Validate(order)
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("поля с ошибками = %v, ожидается %v (%v)", fields, tt.fields, errs)
+			}
+		})
+	}
+}